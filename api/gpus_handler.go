@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// DeviceStore is the source of the device snapshots served by the JSON api.
+type DeviceStore interface {
+	Devices() []nvidiaMetrics.DeviceSnapshot
+	Device(uuid string) (nvidiaMetrics.DeviceSnapshot, bool)
+}
+
+type gpusResponse struct {
+	Gpus []nvidiaMetrics.DeviceSnapshot `json:"gpus"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// registerGpuHandlers adds the JSON api for the current device state to the mux.
+func registerGpuHandlers(mux *http.ServeMux, store DeviceStore) {
+	mux.HandleFunc("GET "+gpusPath, listGpusHandler(store))
+	mux.HandleFunc("GET "+gpusPath+"/{uuid}", getGpuHandler(store))
+}
+
+// listGpusHandler serves the latest snapshot of every GPU.
+func listGpusHandler(store DeviceStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, gpusResponse{Gpus: store.Devices()})
+	}
+}
+
+// getGpuHandler serves the latest snapshot of the GPU with the requested UUID.
+func getGpuHandler(store DeviceStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uuid := r.PathValue("uuid")
+		device, ok := store.Device(uuid)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "gpu not found: " + uuid})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, device)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("failed to write json response", zap.Error(err))
+	}
+}
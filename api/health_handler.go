@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+)
+
+// readyIntervals is the number of collection intervals without a successful collection before the exporter is not ready
+const readyIntervals = 3
+
+type healthCheck struct {
+	Name    string `json:"name"`
+	Ok      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+type healthResponse struct {
+	Status string        `json:"status"`
+	Checks []healthCheck `json:"checks,omitempty"`
+}
+
+// readinessChecker checks the exporter can serve fresh metrics.
+type readinessChecker struct {
+	status       func() nvidiaMetrics.CollectionStatus
+	configLoaded func() bool
+	now          func() time.Time
+}
+
+func newReadinessChecker() *readinessChecker {
+	return &readinessChecker{
+		status:       nvidiaMetrics.Status,
+		configLoaded: prometheusmetrics.ConfigLoaded,
+		now:          time.Now,
+	}
+}
+
+// registerHealthHandlers adds the liveness and readiness probes to the mux.
+func registerHealthHandlers(mux *http.ServeMux, checker *readinessChecker) {
+	mux.HandleFunc("GET "+healthzPath, healthzHandler)
+	mux.HandleFunc("GET "+readyzPath, checker.readyzHandler)
+}
+
+// healthzHandler reports the process is alive.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// readyzHandler reports whether NVML is initialized, the config is loaded, there is at least one device
+// and the last successful collection is recent, so probes stop routing to exporters whose collection loop died.
+func (c *readinessChecker) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := c.checks()
+
+	response := healthResponse{Status: "ready", Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if !check.Ok {
+			response.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	writeJSON(w, status, response)
+}
+
+func (c *readinessChecker) checks() []healthCheck {
+	status := c.status()
+
+	nvml := healthCheck{Name: "nvml", Ok: status.NvmlInitialized}
+	if !nvml.Ok {
+		nvml.Message = "NVML is not initialized"
+	}
+
+	config := healthCheck{Name: "config", Ok: c.configLoaded()}
+	if !config.Ok {
+		config.Message = "metrics config is not loaded"
+	}
+
+	devices := healthCheck{Name: "devices", Ok: status.DeviceCount > 0, Message: fmt.Sprintf("%d GPUs found", status.DeviceCount)}
+
+	return []healthCheck{nvml, config, devices, c.collectionCheck(status)}
+}
+
+// collectionCheck checks the last collection succeeded within readyIntervals default collection intervals.
+func (c *readinessChecker) collectionCheck(status nvidiaMetrics.CollectionStatus) healthCheck {
+	check := healthCheck{Name: "collection"}
+
+	if status.LastSuccess.IsZero() {
+		check.Message = "no successful collection yet"
+		if status.LastError != "" {
+			check.Message += ": " + status.LastError
+		}
+		return check
+	}
+
+	age := c.now().Sub(status.LastSuccess)
+	// the default interval, a short tick of a few fast metrics would make the probe flap on a slow collection
+	if status.DefaultInterval > 0 && age > readyIntervals*status.DefaultInterval {
+		check.Message = fmt.Sprintf("last successful collection %v ago", age.Round(time.Second))
+		if status.LastError != "" {
+			check.Message += ": " + status.LastError
+		}
+		return check
+	}
+
+	check.Ok = true
+	check.Message = fmt.Sprintf("last successful collection at %v", status.LastSuccess.Format(time.RFC3339))
+	return check
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+)
+
+func TestReadyz(t *testing.T) {
+	now := time.Now()
+	ready := nvidiaMetrics.CollectionStatus{
+		NvmlInitialized: true,
+		DeviceCount:     1,
+		Interval:        5 * time.Second,
+		DefaultInterval: 5 * time.Second,
+		LastSuccess:     now.Add(-5 * time.Second),
+	}
+
+	tests := []struct {
+		name         string
+		status       nvidiaMetrics.CollectionStatus
+		configLoaded bool
+		expected     int
+	}{
+		{"Ready", ready, true, http.StatusOK},
+		{"ConfigNotLoaded", ready, false, http.StatusServiceUnavailable},
+		{"NvmlNotInitialized", func() nvidiaMetrics.CollectionStatus { s := ready; s.NvmlInitialized = false; return s }(), true, http.StatusServiceUnavailable},
+		{"NoDevices", func() nvidiaMetrics.CollectionStatus { s := ready; s.DeviceCount = 0; return s }(), true, http.StatusServiceUnavailable},
+		{"NoCollection", func() nvidiaMetrics.CollectionStatus { s := ready; s.LastSuccess = time.Time{}; return s }(), true, http.StatusServiceUnavailable},
+		{"StaleCollection", func() nvidiaMetrics.CollectionStatus { s := ready; s.LastSuccess = now.Add(-time.Minute); return s }(), true, http.StatusServiceUnavailable},
+		{"ShortTick", func() nvidiaMetrics.CollectionStatus {
+			s := ready
+			s.Interval = time.Second
+			s.DefaultInterval = 30 * time.Second
+			s.LastSuccess = now.Add(-10 * time.Second)
+			return s
+		}(), true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			checker := &readinessChecker{
+				status:       func() nvidiaMetrics.CollectionStatus { return tt.status },
+				configLoaded: func() bool { return tt.configLoaded },
+				now:          func() time.Time { return now },
+			}
+			mux := http.NewServeMux()
+			registerHealthHandlers(mux, checker)
+
+			// Act
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			// Assert
+			if recorder.Code != tt.expected {
+				t.Errorf("Expected status %v, got %v: %v", tt.expected, recorder.Code, recorder.Body.String())
+			}
+
+			var response healthResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Expected json response, got %v", err)
+			}
+			if len(response.Checks) != 4 {
+				t.Errorf("Expected 4 checks, got %v", len(response.Checks))
+			}
+		})
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	mux := http.NewServeMux()
+	registerHealthHandlers(mux, newReadinessChecker())
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %v, got %v", http.StatusOK, recorder.Code)
+	}
+}
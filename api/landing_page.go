@@ -0,0 +1,110 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var landingPageTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Nvidia Metrics Exporter</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Nvidia Metrics Exporter</h1>
+<ul>
+{{range .Links}}<li><a href="{{.Path}}">{{.Path}}</a> {{.Description}}</li>
+{{end}}</ul>
+
+<h2>Devices</h2>
+{{if .Devices}}<table>
+<tr><th>Index</th><th>Name</th><th>UUID</th><th>Last collected</th></tr>
+{{range .Devices}}<tr><td>{{.Index}}</td><td>{{.Name}}</td><td><a href="/api/v1/gpus/{{.UUID}}">{{.UUID}}</a></td><td>{{.Timestamp.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+{{end}}</table>
+{{else}}<p>No devices collected yet.</p>
+{{end}}
+<h2>Enabled metrics</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Unit</th><th>Interval</th></tr>
+{{range .Metrics}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Unit}}</td><td>{{.Interval}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type landingLink struct {
+	Path        string
+	Description string
+}
+
+type landingMetric struct {
+	Name     string
+	Type     string
+	Unit     string
+	Interval time.Duration
+}
+
+type landingPage struct {
+	Links   []landingLink
+	Devices []nvidiaMetrics.DeviceSnapshot
+	Metrics []landingMetric
+}
+
+// landingPageHandler serves an HTML page listing the endpoints, the devices and the enabled metrics.
+func landingPageHandler(telemetryPath string, store DeviceStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page := landingPage{
+			Links: []landingLink{
+				{Path: telemetryPath, Description: "Prometheus metrics"},
+				{Path: "/api/v1/gpus", Description: "Device state as JSON"},
+				{Path: "/healthz", Description: "Liveness probe"},
+				{Path: "/readyz", Description: "Readiness probe"},
+				{Path: "/debug/loglevel", Description: "Log level, PUT {\"level\":\"debug\"} to change it"},
+			},
+			Devices: store.Devices(),
+			Metrics: enabledMetrics(),
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := landingPageTemplate.Execute(w, page); err != nil {
+			logger.Error("failed to render landing page", zap.Error(err))
+		}
+	}
+}
+
+// enabledMetrics lists the registered gauges and counters ordered by name.
+func enabledMetrics() []landingMetric {
+	metrics := make([]landingMetric, 0, len(prometheusmetrics.RegisteredMetrics)+len(prometheusmetrics.RegisteredCounters))
+	for name := range prometheusmetrics.RegisteredMetrics {
+		metrics = append(metrics, newLandingMetric(name, "gauge"))
+	}
+	for name := range prometheusmetrics.RegisteredCounters {
+		metrics = append(metrics, newLandingMetric(name, "counter"))
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics
+}
+
+func newLandingMetric(name string, metricType string) landingMetric {
+	return landingMetric{
+		Name:     name,
+		Type:     metricType,
+		Unit:     prometheusmetrics.RegisteredUnits.GetUnit(name),
+		Interval: nvidiaMetrics.CollectionInterval(name),
+	}
+}
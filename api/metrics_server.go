@@ -2,13 +2,16 @@ package api
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
 	"go.uber.org/zap"
@@ -21,46 +24,216 @@ var (
 	})
 )
 
-func RunPrometheusMetricsServer(ctx context.Context, address string, interval time.Duration) {
+// defaultTelemetryPath is the path the Prometheus metrics are served at unless configured
+const defaultTelemetryPath = "/metrics"
+
+// shutdownTimeout bounds how long in-flight scrapes are drained on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// ServerOptions configures the metrics HTTP server.
+type ServerOptions struct {
+	Address string
+	// TelemetryPath is the path the Prometheus metrics are served at, /metrics if empty
+	TelemetryPath string
+	// WebConfigFile is an exporter-toolkit web config enabling TLS, mTLS and basic auth, plain HTTP if empty
+	WebConfigFile string
+	// ProbeAddress serves only /healthz and /readyz over plain HTTP outside the web config,
+	// so kubelet probes need neither credentials nor a client certificate, disabled if empty
+	ProbeAddress string
+	// DisableServer doesn't serve the metrics over HTTP, they are only pushed
+	DisableServer bool
+	// Pushers push the collected metrics next to the HTTP server
+	Pushers []Pusher
+}
+
+// Pusher pushes the collected metrics to a remote system until the context is cancelled.
+type Pusher interface {
+	Run(ctx context.Context) error
+}
+
+func (o ServerOptions) telemetryPath() string {
+	if o.TelemetryPath == "" {
+		return defaultTelemetryPath
+	}
+	return o.TelemetryPath
+}
+
+// RunPrometheusMetricsServer collects the GPU metrics and serves them until the context is cancelled.
+// NVML is shut down once the collection loop and the event monitor have stopped.
+func RunPrometheusMetricsServer(ctx context.Context, options ServerOptions, interval time.Duration) error {
 	// Initialize NVML before starting the metric collection loop
 	nvidiaMetrics.InitNVML()
 	defer nvidiaMetrics.ShutdownNVML()
 
-	// Start collecting GPU metrics every 5 seconds
-	startMetricsCollection(ctx, interval)
+	// Stop collecting when the server fails as well
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start collecting GPU metrics every interval
+	collectionDone := startMetricsCollection(ctx, interval)
+
+	// Start counting XID and other NVML events
+	eventsDone := nvidiaMetrics.StartEventMonitor(ctx)
+
+	// Start pushing the metrics, a stopped pusher stops the exporter like a failed server
+	pushErrs := startPushers(ctx, cancel, options.Pushers)
 
 	// Start the HTTP server to expose metrics
-	err := StartPrometheusServer(address)
-	if err != nil {
-		logger.Fatal("HTTP server failed", zap.Error(err))
+	var err error
+	if options.DisableServer {
+		<-ctx.Done()
+	} else {
+		err = StartPrometheusServer(ctx, options)
+		if err != nil {
+			logger.Error("HTTP server failed", zap.Error(err))
+		}
 	}
+
+	cancel()
+	<-collectionDone
+	<-eventsDone
+
+	for pushErr := range pushErrs {
+		logger.Error("Metrics pusher failed", zap.Error(pushErr))
+		err = errors.Join(err, pushErr)
+	}
+
+	return err
+}
+
+// startPushers runs the pushers until the context is cancelled, cancelling it when one stops,
+// either failed or done like a one-shot push.
+// The returned channel yields the errors of the pushers and is closed once they all stopped.
+func startPushers(ctx context.Context, cancel context.CancelFunc, pushers []Pusher) <-chan error {
+	errs := make(chan error, len(pushers))
+
+	var wg sync.WaitGroup
+	for _, pusher := range pushers {
+		wg.Add(1)
+		go func(pusher Pusher) {
+			defer wg.Done()
+			defer cancel()
+			if err := pusher.Run(ctx); err != nil {
+				errs <- err
+			}
+		}(pusher)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errs)
+	}()
+
+	return errs
 }
 
-func startMetricsCollection(ctx context.Context, interval time.Duration) {
+// startMetricsCollection runs the collection loop until the context is cancelled.
+// The returned channel is closed once the loop stopped.
+func startMetricsCollection(ctx context.Context, interval time.Duration) <-chan struct{} {
+	// Metrics with their own interval are collected when due, the loop ticks at the shortest interval
+	tick := nvidiaMetrics.ConfigureSchedule(interval)
+	logger.Info("Starting metrics collection", zap.Duration("interval", interval), zap.Duration("tick", tick))
+
+	done := make(chan struct{})
 	go func() {
-		ticker := time.NewTicker(interval)
+		defer close(done)
+
+		// collect right away, so a short run or the first push doesn't wait a whole tick
+		nvidiaMetrics.CollectGpuMetrics(ctx)
+		opsProcessed.Inc()
+
+		ticker := time.NewTicker(tick)
 		defer ticker.Stop()
-		for range ticker.C {
-			nvidiaMetrics.CollectGpuMetrics(ctx)
-			opsProcessed.Inc()
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Info("Stopped metrics collection")
+				return
+			case <-ticker.C:
+				nvidiaMetrics.CollectGpuMetrics(ctx)
+				opsProcessed.Inc()
+			}
 		}
 	}()
+
+	return done
 }
 
-func StartPrometheusServer(address string) error {
+// StartPrometheusServer serves the metrics until the context is cancelled, then drains in-flight requests.
+// The web config is read on every new connection, so renewed certificates are picked up without a restart.
+// The probe server, if configured, runs next to it and a failure of either stops both.
+func StartPrometheusServer(ctx context.Context, options ServerOptions) error {
+	telemetryPath := options.telemetryPath()
+
+	mux, err := newServerMux(telemetryPath)
+	if err != nil {
+		return err
+	}
+
 	server := &http.Server{
-		Addr:         address,
+		Addr:         options.Address,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
-		Handler:      promhttp.Handler(),
+		Handler:      mux,
 	}
 
-	logger.Info("Starting Prometheus server", zap.String("address", address), zap.String("path", "/metrics"))
+	logger.Info("Starting Prometheus server",
+		zap.String("address", options.Address),
+		zap.String("path", telemetryPath),
+		zap.String("api", gpusPath),
+		zap.String("web_config_file", options.WebConfigFile),
+	)
 
-	err := server.ListenAndServe()
+	systemdSocket := false
+	flags := &web.FlagConfig{
+		WebListenAddresses: &[]string{options.Address},
+		WebSystemdSocket:   &systemdSocket,
+		WebConfigFile:      &options.WebConfigFile,
+	}
 
-	return err
+	servers := []*http.Server{server}
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- web.ListenAndServe(server, flags, logger.SlogLogger())
+	}()
 
+	if options.ProbeAddress != "" {
+		probeServer := &http.Server{
+			Addr:         options.ProbeAddress,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			Handler:      newProbeMux(),
+		}
+		servers = append(servers, probeServer)
+
+		logger.Info("Starting probe server", zap.String("address", options.ProbeAddress))
+		go func() {
+			serverErr <- probeServer.ListenAndServe()
+		}()
+	}
+
+	running := len(servers)
+	select {
+	case err = <-serverErr:
+		running--
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down Prometheus server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, s := range servers {
+		err = errors.Join(err, s.Shutdown(shutdownCtx))
+	}
+
+	for ; running > 0; running-- {
+		if serveErr := <-serverErr; !errors.Is(serveErr, http.ErrServerClosed) {
+			err = errors.Join(err, serveErr)
+		}
+	}
+
+	return err
 }
 
 // @TODO Remove after testing
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = logger.GetLogger("debug", false, "")
+
+func TestStartPrometheusServerShutdown(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	serverErr := make(chan error, 1)
+
+	// Act
+	go func() {
+		serverErr <- StartPrometheusServer(ctx, ServerOptions{Address: "127.0.0.1:0"})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	// Assert
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Errorf("Expected graceful shutdown, got %v", err)
+		}
+	case <-time.After(shutdownTimeout):
+		t.Fatal("Expected server to stop after the context was cancelled")
+	}
+}
+
+func TestStartPrometheusServerFails(t *testing.T) {
+	err := StartPrometheusServer(context.Background(), ServerOptions{Address: "invalid-address"})
+	if err == nil {
+		t.Error("Expected error for invalid address")
+	}
+}
+
+func TestStartPrometheusServerBasicAuth(t *testing.T) {
+	// Arrange
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webConfigFile := filepath.Join(t.TempDir(), "web-config.yml")
+	webConfig := fmt.Sprintf("basic_auth_users:\n  prometheus: %s\n", hash)
+	if err := os.WriteFile(webConfigFile, []byte(webConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	address := freeAddress(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go StartPrometheusServer(ctx, ServerOptions{Address: address, WebConfigFile: webConfigFile})
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		expected int
+	}{
+		{"NoCredentials", "", "", http.StatusUnauthorized},
+		{"WrongPassword", "prometheus", "wrong", http.StatusUnauthorized},
+		{"ValidCredentials", "prometheus", "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			request, _ := http.NewRequest(http.MethodGet, "http://"+address+"/healthz", nil)
+			if tt.user != "" {
+				request.SetBasicAuth(tt.user, tt.password)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+
+			// Assert
+			if response.StatusCode != tt.expected {
+				t.Errorf("Expected status %v, got %v", tt.expected, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestStartPrometheusServerProbeAddress(t *testing.T) {
+	// Arrange
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webConfigFile := filepath.Join(t.TempDir(), "web-config.yml")
+	webConfig := fmt.Sprintf("basic_auth_users:\n  prometheus: %s\n", hash)
+	if err := os.WriteFile(webConfigFile, []byte(webConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	address := freeAddress(t)
+	probeAddress := freeAddress(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go StartPrometheusServer(ctx, ServerOptions{Address: address, WebConfigFile: webConfigFile, ProbeAddress: probeAddress})
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		name     string
+		url      string
+		expected int
+	}{
+		{"ServerHealthz", "http://" + address + "/healthz", http.StatusUnauthorized},
+		{"ProbeHealthz", "http://" + probeAddress + "/healthz", http.StatusOK},
+		{"ProbeMetrics", "http://" + probeAddress + "/metrics", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			response, err := http.Get(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+
+			// Assert
+			if response.StatusCode != tt.expected {
+				t.Errorf("Expected status %v, got %v", tt.expected, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestStartPrometheusServerProbeAddressFails(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	err := StartPrometheusServer(ctx, ServerOptions{Address: freeAddress(t), ProbeAddress: "invalid-address"})
+
+	// Assert
+	if err == nil {
+		t.Error("Expected error for invalid probe address")
+	}
+}
+
+// freeAddress returns a local address no server is listening on.
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+type pusherFunc func(ctx context.Context) error
+
+func (f pusherFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestStartPushersCancelsOnFailure(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := pusherFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	failed := pusherFunc(func(ctx context.Context) error {
+		return fmt.Errorf("endpoint unreachable")
+	})
+
+	// Act
+	errs := startPushers(ctx, cancel, []Pusher{stopped, failed})
+
+	// Assert
+	var got []error
+	for err := range errs {
+		got = append(got, err)
+	}
+	if len(got) != 1 || got[0].Error() != "endpoint unreachable" {
+		t.Errorf("Expected the error of the failed pusher, got %v", got)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the context to be cancelled after a pusher failed")
+	}
+}
+
+func TestStartPushersCancelsWhenDone(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	once := pusherFunc(func(ctx context.Context) error {
+		return nil
+	})
+
+	// Act
+	errs := startPushers(ctx, cancel, []Pusher{once})
+
+	// Assert
+	for err := range errs {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the context to be cancelled after a one-shot pusher is done")
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+)
+
+// Routes served next to the metrics, the telemetry path can't be one of them
+const (
+	gpusPath     = "/api/v1/gpus"
+	healthzPath  = "/healthz"
+	readyzPath   = "/readyz"
+	logLevelPath = "/debug/loglevel"
+)
+
+// reservedPaths are the routes of the server, a telemetry path on them would shadow or conflict with the route
+var reservedPaths = []string{"/", gpusPath, healthzPath, readyzPath, logLevelPath}
+
+// ValidateTelemetryPath checks the path can be routed next to the other routes of the server.
+// It has to be a plain path, ServeMux pattern syntax such as wildcards or a method isn't allowed.
+func ValidateTelemetryPath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid telemetry path %q, it must start with /", path)
+	}
+
+	if strings.ContainsAny(path, "{} \t") {
+		return fmt.Errorf("invalid telemetry path %q, it can't contain wildcards or whitespace", path)
+	}
+
+	for _, reserved := range reservedPaths {
+		if path == reserved {
+			return fmt.Errorf("invalid telemetry path %q, it is reserved for another route", path)
+		}
+	}
+
+	// the GPU api routes every path below it
+	if strings.HasPrefix(path, gpusPath+"/") {
+		return fmt.Errorf("invalid telemetry path %q, it is below the %s route", path, gpusPath)
+	}
+
+	return nil
+}
+
+// newServerMux routes the metrics, the JSON api, the probes, the log level and the landing page.
+// Unknown paths return 404 instead of metrics.
+func newServerMux(telemetryPath string) (mux *http.ServeMux, err error) {
+	err = ValidateTelemetryPath(telemetryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// ServeMux panics on conflicting patterns, report them like an invalid path
+	defer func() {
+		if r := recover(); r != nil {
+			mux, err = nil, fmt.Errorf("invalid telemetry path %q: %v", telemetryPath, r)
+		}
+	}()
+
+	mux = http.NewServeMux()
+	mux.Handle(telemetryPath, promhttp.Handler())
+	mux.HandleFunc("GET /{$}", landingPageHandler(telemetryPath, nvidiaMetrics.Snapshots()))
+	registerGpuHandlers(mux, nvidiaMetrics.Snapshots())
+	registerHealthHandlers(mux, newReadinessChecker())
+	mux.Handle(logLevelPath, logger.LevelHandler())
+	return mux, nil
+}
+
+// newProbeMux routes only the probes, it is served without the web config.
+func newProbeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	registerHealthHandlers(mux, newReadinessChecker())
+	return mux
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerMuxRoutes(t *testing.T) {
+	mux, err := newServerMux("/custom-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+		contains string
+	}{
+		{"LandingPage", "/", http.StatusOK, "Nvidia Metrics Exporter"},
+		{"LandingPageLinksTelemetryPath", "/", http.StatusOK, `href="/custom-metrics"`},
+		{"TelemetryPath", "/custom-metrics", http.StatusOK, ""},
+		{"DefaultMetricsPathNotServed", "/metrics", http.StatusNotFound, ""},
+		{"UnknownPath", "/unknown", http.StatusNotFound, ""},
+		{"GpusApi", "/api/v1/gpus", http.StatusOK, `"gpus"`},
+		{"Healthz", "/healthz", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			// Assert
+			if recorder.Code != tt.expected {
+				t.Errorf("Expected status %v for %v, got %v", tt.expected, tt.path, recorder.Code)
+			}
+			if tt.contains != "" && !strings.Contains(recorder.Body.String(), tt.contains) {
+				t.Errorf("Expected %v in response for %v", tt.contains, tt.path)
+			}
+		})
+	}
+}
+
+func TestValidateTelemetryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"DefaultPath", "/metrics", false},
+		{"NestedPath", "/exporter/metrics", false},
+		{"MissingSlash", "metrics", true},
+		{"RootPath", "/", true},
+		{"Healthz", "/healthz", true},
+		{"Readyz", "/readyz", true},
+		{"LogLevel", "/debug/loglevel", true},
+		{"GpusApi", "/api/v1/gpus", true},
+		{"BelowGpusApi", "/api/v1/gpus/metrics", true},
+		{"Wildcard", "/metrics/{name}", true},
+		{"Method", "/metrics GET", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTelemetryPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v for %v, got %v", tt.wantErr, tt.path, err)
+			}
+		})
+	}
+}
+
+func TestStartPrometheusServerInvalidTelemetryPath(t *testing.T) {
+	err := StartPrometheusServer(context.Background(), ServerOptions{Address: "127.0.0.1:0", TelemetryPath: "/debug/loglevel"})
+	if err == nil {
+		t.Fatal("Expected an error for a reserved telemetry path")
+	}
+}
@@ -5,14 +5,21 @@ import (
 	"flag"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/rupeshtr78/nvidia-metrics/api"
 	nvidiametrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	otlpmetrics "github.com/rupeshtr78/nvidia-metrics/internal/otlp_metrics"
 	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+	"github.com/rupeshtr78/nvidia-metrics/internal/pushgateway"
+	remotewrite "github.com/rupeshtr78/nvidia-metrics/internal/remote_write"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/utils"
 	"go.uber.org/zap"
 )
 
@@ -24,6 +31,32 @@ func RunServer() {
 	interval := getEnv("INTERVAL", "5")
 	logFilePath := getEnv("LOG_FILE_PATH", "logs/gpu-metrics.log")
 	logToFile := getEnv("LOG_TO_FILE", "false")
+	logFormat := getEnv("LOG_FORMAT", "json")
+	logMaxSize := getEnv("LOG_MAX_SIZE", "100")
+	logMaxAge := getEnv("LOG_MAX_AGE", "28")
+	logMaxBackups := getEnv("LOG_MAX_BACKUPS", "5")
+	logCompress := getEnv("LOG_COMPRESS", "true")
+	logSyslog := getEnv("LOG_SYSLOG", "false")
+	logSampling := getEnv("LOG_SAMPLING", "true")
+	webConfigFile := getEnv("WEB_CONFIG_FILE", "")
+	telemetryPath := getEnv("TELEMETRY_PATH", "/metrics")
+	webDisable := getEnv("WEB_DISABLE", "false")
+	webProbeAddress := getEnv("WEB_PROBE_ADDRESS", "")
+	otlpEndpoint := getEnv("OTLP_ENDPOINT", "")
+	otlpProtocol := getEnv("OTLP_PROTOCOL", otlpmetrics.ProtocolGRPC)
+	otlpInterval := getEnv("OTLP_INTERVAL", "30")
+	otlpHeaders := getEnv("OTLP_HEADERS", "")
+	remoteWriteURL := getEnv("REMOTE_WRITE_URL", "")
+	remoteWriteInterval := getEnv("REMOTE_WRITE_INTERVAL", "30")
+	remoteWriteBatchSize := getEnv("REMOTE_WRITE_BATCH_SIZE", "2000")
+	remoteWriteWALDir := getEnv("REMOTE_WRITE_WAL_DIR", "")
+	remoteWriteWALMaxSize := getEnv("REMOTE_WRITE_WAL_MAX_SIZE", "256")
+	pushgatewayURL := getEnv("PUSHGATEWAY_URL", "")
+	pushgatewayJob := getEnv("PUSHGATEWAY_JOB", pushgateway.DefaultJob)
+	pushgatewayGrouping := getEnv("PUSHGATEWAY_GROUPING", "")
+	pushgatewayInterval := getEnv("PUSHGATEWAY_INTERVAL", "30")
+	pushgatewayOnce := getEnv("PUSHGATEWAY_ONCE", "false")
+	pushgatewayDelete := getEnv("PUSHGATEWAY_DELETE_ON_SHUTDOWN", "true")
 
 	flag.StringVar(&configFile, "config", configFile, "Path to the configuration file")
 	flag.StringVar(&logLevel, "loglevel", logLevel, "Log level (debug, info, warn, error,fatal)")
@@ -32,6 +65,32 @@ func RunServer() {
 	flag.StringVar(&interval, "interval", interval, "Time interval in seconds to scrape metrics")
 	flag.StringVar(&logFilePath, "logfile", logFilePath, "Log file path")
 	flag.StringVar(&logToFile, "filelog", logToFile, "Enable file logging")
+	flag.StringVar(&logFormat, "log.format", logFormat, "Log encoding (json, console)")
+	flag.StringVar(&logMaxSize, "log.max-size", logMaxSize, "Size in megabytes before the log file is rotated")
+	flag.StringVar(&logMaxAge, "log.max-age", logMaxAge, "Days to retain rotated log files, 0 keeps them")
+	flag.StringVar(&logMaxBackups, "log.max-backups", logMaxBackups, "Number of rotated log files to retain, 0 keeps them")
+	flag.StringVar(&logCompress, "log.compress", logCompress, "Compress rotated log files")
+	flag.StringVar(&logSyslog, "log.syslog", logSyslog, "Also log to the local syslog daemon, picked up by journald")
+	flag.StringVar(&logSampling, "log.sampling", logSampling, "Sample repeated warnings and errors with the same message, the first 5 per minute and every 100th after that")
+	flag.StringVar(&webConfigFile, "web.config.file", webConfigFile, "Path to the web config file enabling TLS and basic auth")
+	flag.StringVar(&telemetryPath, "web.telemetry-path", telemetryPath, "Path under which to expose metrics, not one of the other routes such as /healthz or /api/v1/gpus")
+	flag.StringVar(&webDisable, "web.disable", webDisable, "Don't serve the metrics over HTTP, only push them")
+	flag.StringVar(&webProbeAddress, "web.probe-address", webProbeAddress, "Address to serve /healthz and /readyz on over plain HTTP without the web config, e.g. :9501, disabled if empty")
+	flag.StringVar(&otlpEndpoint, "otlp.endpoint", otlpEndpoint, "URL of the OpenTelemetry collector to push metrics to, e.g. http://localhost:4317, disabled if empty")
+	flag.StringVar(&otlpProtocol, "otlp.protocol", otlpProtocol, "OTLP protocol (grpc, http/protobuf)")
+	flag.StringVar(&otlpInterval, "otlp.interval", otlpInterval, "Time interval in seconds to push metrics over OTLP")
+	flag.StringVar(&otlpHeaders, "otlp.headers", otlpHeaders, "Headers to send with every OTLP push as name=value pairs separated by commas, e.g. Authorization=Bearer <token>")
+	flag.StringVar(&remoteWriteURL, "remote-write.url", remoteWriteURL, "URL of the Prometheus remote-write endpoint to push metrics to, disabled if empty")
+	flag.StringVar(&remoteWriteInterval, "remote-write.interval", remoteWriteInterval, "Time interval in seconds to push metrics over remote-write")
+	flag.StringVar(&remoteWriteBatchSize, "remote-write.batch-size", remoteWriteBatchSize, "Maximum number of samples per remote-write request")
+	flag.StringVar(&remoteWriteWALDir, "remote-write.wal-dir", remoteWriteWALDir, "Directory to keep samples in until they are sent, required with remote-write.url, e.g. /var/lib/nvidia-metrics/remote-write")
+	flag.StringVar(&remoteWriteWALMaxSize, "remote-write.wal-max-size", remoteWriteWALMaxSize, "Size in megabytes of the WAL before the oldest samples are dropped")
+	flag.StringVar(&pushgatewayURL, "pushgateway.url", pushgatewayURL, "URL of the Pushgateway to push metrics to, disabled if empty")
+	flag.StringVar(&pushgatewayJob, "pushgateway.job", pushgatewayJob, "Job label of the Pushgateway group")
+	flag.StringVar(&pushgatewayGrouping, "pushgateway.grouping", pushgatewayGrouping, "Grouping labels next to job and instance, e.g. ci_job_id=1234,benchmark=resnet50")
+	flag.StringVar(&pushgatewayInterval, "pushgateway.interval", pushgatewayInterval, "Time interval in seconds to push metrics to the Pushgateway")
+	flag.StringVar(&pushgatewayOnce, "pushgateway.once", pushgatewayOnce, "Push the metrics of the first collection and exit, the group is kept")
+	flag.StringVar(&pushgatewayDelete, "pushgateway.delete-on-shutdown", pushgatewayDelete, "Delete the Pushgateway group on a clean shutdown")
 
 	flag.Parse()
 
@@ -39,13 +98,24 @@ func RunServer() {
 		log.Fatal("Config file is required")
 	}
 
-	fileLogBool, err := strconv.ParseBool(logToFile)
-	if err != nil {
-		log.Fatal("Failed to convert file log to boolean", err)
+	if err := api.ValidateTelemetryPath(telemetryPath); err != nil {
+		log.Fatal(err)
 	}
 
+	logOptions := logger.DefaultOptions()
+	logOptions.Level = logLevel
+	logOptions.Encoding = logFormat
+	logOptions.FilePath = logFilePath
+	logOptions.FileLog = parseBool("filelog", logToFile)
+	logOptions.MaxSizeMB = parseInt("log.max-size", logMaxSize)
+	logOptions.MaxAgeDays = parseInt("log.max-age", logMaxAge)
+	logOptions.MaxBackups = parseInt("log.max-backups", logMaxBackups)
+	logOptions.Compress = parseBool("log.compress", logCompress)
+	logOptions.Syslog = parseBool("log.syslog", logSyslog)
+	logOptions.Sampling = parseBool("log.sampling", logSampling)
+
 	// Initialize the logger
-	err = logger.GetLogger(logLevel, fileLogBool, logFilePath)
+	err := logger.InitLogger(logOptions)
 	if err != nil {
 		log.Fatal("Failed to initialize logger", err)
 	}
@@ -62,9 +132,79 @@ func RunServer() {
 		os.Exit(1)
 	}
 
+	// Fail early on an invalid web config instead of on the first connection
+	if webConfigFile != "" {
+		err = web.Validate(webConfigFile)
+		if err != nil {
+			logger.Fatal("Invalid web config file", zap.String("file", webConfigFile), zap.Error(err))
+		}
+	}
+
 	// get the address from the host and port
 	address := host + ":" + port
 
+	var pushers []api.Pusher
+	if otlpEndpoint != "" {
+		headers, err := utils.ParsePairs(otlpHeaders)
+		if err != nil {
+			logger.Fatal("Invalid OTLP headers", zap.Error(err))
+		}
+
+		otlpExporter, err := otlpmetrics.NewExporter(otlpmetrics.Options{
+			Endpoint: otlpEndpoint,
+			Protocol: otlpProtocol,
+			Interval: time.Duration(parseInt("otlp.interval", otlpInterval)) * time.Second,
+			Headers:  headers,
+		})
+		if err != nil {
+			logger.Fatal("Invalid OTLP configuration", zap.Error(err))
+		}
+		pushers = append(pushers, otlpExporter)
+	}
+
+	if remoteWriteURL != "" {
+		remoteWriteClient, err := remotewrite.NewClient(remotewrite.Options{
+			URL:            remoteWriteURL,
+			Interval:       time.Duration(parseInt("remote-write.interval", remoteWriteInterval)) * time.Second,
+			BatchSize:      parseInt("remote-write.batch-size", remoteWriteBatchSize),
+			WALDir:         remoteWriteWALDir,
+			WALMaxSize:     int64(parseInt("remote-write.wal-max-size", remoteWriteWALMaxSize)) << 20,
+			ExternalLabels: map[string]string{"job": "nvidia-metrics", "instance": hostname()},
+		})
+		if err != nil {
+			logger.Fatal("Invalid remote-write configuration", zap.Error(err))
+		}
+		pushers = append(pushers, remoteWriteClient)
+	}
+
+	if pushgatewayURL != "" {
+		grouping, err := pushgateway.ParseGrouping(pushgatewayGrouping)
+		if err != nil {
+			logger.Fatal("Invalid Pushgateway grouping", zap.Error(err))
+		}
+		if _, ok := grouping["instance"]; !ok {
+			grouping["instance"] = hostname()
+		}
+
+		pushgatewayPusher, err := pushgateway.NewPusher(pushgateway.Options{
+			URL:              pushgatewayURL,
+			Job:              pushgatewayJob,
+			Grouping:         grouping,
+			Interval:         time.Duration(parseInt("pushgateway.interval", pushgatewayInterval)) * time.Second,
+			Once:             parseBool("pushgateway.once", pushgatewayOnce),
+			DeleteOnShutdown: parseBool("pushgateway.delete-on-shutdown", pushgatewayDelete),
+		})
+		if err != nil {
+			logger.Fatal("Invalid Pushgateway configuration", zap.Error(err))
+		}
+		pushers = append(pushers, pushgatewayPusher)
+	}
+
+	disableServer := parseBool("web.disable", webDisable)
+	if disableServer && len(pushers) == 0 {
+		logger.Fatal("The HTTP server is disabled and no push mode is configured, nothing would export the metrics")
+	}
+
 	//  get the metrics scrape interval
 	t, err := strconv.Atoi(interval)
 	if err != nil {
@@ -78,12 +218,59 @@ func RunServer() {
 	}
 
 	// Start the metrics server with a long-running context
-	// ctxRunServer uses context.WithCancel to ensure the server can run indefinitely until explicitly canceled.
-	ctxRunServer, cancelRunServer := context.WithCancel(context.Background())
+	// ctxRunServer is cancelled on SIGINT or SIGTERM to shut the server down gracefully.
+	ctxRunServer, cancelRunServer := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancelRunServer()
 
+	// SIGUSR1 and SIGUSR2 make the logs more or less verbose without a restart
+	logger.WatchLevelSignals(ctxRunServer)
+
 	// start the metrics server
-	api.RunPrometheusMetricsServer(ctxRunServer, address, scrapreInterval)
+	serverOptions := api.ServerOptions{
+		Address:       address,
+		TelemetryPath: telemetryPath,
+		WebConfigFile: webConfigFile,
+		ProbeAddress:  webProbeAddress,
+		DisableServer: disableServer,
+		Pushers:       pushers,
+	}
+	err = api.RunPrometheusMetricsServer(ctxRunServer, serverOptions, scrapreInterval)
+	if err != nil {
+		logger.Error("Metrics server failed", zap.Error(err))
+		logger.Sync()
+		os.Exit(1)
+	}
+
+	logger.Info("Metrics server stopped")
+	logger.Sync()
+}
+
+// parseBool parses a boolean flag value, exiting on invalid values as the logger isn't initialized yet.
+func parseBool(name string, value string) bool {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Failed to convert %s to boolean: %v", name, err)
+	}
+	return b
+}
+
+// parseInt parses an integer flag value, exiting on invalid values as the logger isn't initialized yet.
+func parseInt(name string, value string) int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Failed to convert %s to integer: %v", name, err)
+	}
+	return i
+}
+
+// hostname returns the host name used as the instance of pushed metrics, which a scrape would set.
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		logger.Warn("Failed to get the hostname", zap.Error(err))
+		return "unknown"
+	}
+	return name
 }
 
 // getEnv reads an environment variable or returns a default value.
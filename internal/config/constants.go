@@ -11,15 +11,14 @@ import (
 type Metric string
 
 const (
-	GPU_ID_METRIC              Metric = "gpu_id_metric"
 	GPU_GPU_UTILIZATION        Metric = "gpu_gpu_utilization"
 	GPU_MEM_UTILIZATION        Metric = "gpu_mem_utilization"
 	GPU_POWER_USAGE            Metric = "gpu_power_usage"
 	GPU_RUNNING_PROCESS        Metric = "gpu_running_process"
 	GPU_TEMPERATURE            Metric = "gpu_temperature"
-	GPU_MEMORY_USED            Metric = "gpu_memory_used"
-	GPU_MEMORY_TOTAL           Metric = "gpu_memory_total"
-	GPU_MEMORY_FREE            Metric = "gpu_memory_free"
+	GPU_MEMORY_USED            Metric = "gpu_memory_used_bytes"
+	GPU_MEMORY_TOTAL           Metric = "gpu_memory_total_bytes"
+	GPU_MEMORY_FREE            Metric = "gpu_memory_free_bytes"
 	GPU_P_STATE                Metric = "gpu_p_state"
 	GPU_MEMORY_CLOCK           Metric = "gpu_memory_clock"
 	GPU_GRAPHICS_CLOCK         Metric = "gpu_graphics_clock"
@@ -29,6 +28,54 @@ const (
 	GPU_ECC_UNCORRECTED_ERRORS Metric = "gpu_ecc_uncorrected_errors"
 	GPU_FAN_SPEED              Metric = "gpu_fan_speed"
 	GPU_PEAK_FLOPS_METRIC      Metric = "gpu_peak_flops_metric"
+
+	GPU_TEMPERATURE_SHUTDOWN_THRESHOLD Metric = "gpu_temperature_shutdown_threshold"
+	GPU_TEMPERATURE_SLOWDOWN_THRESHOLD Metric = "gpu_temperature_slowdown_threshold"
+	GPU_TEMPERATURE_MEM_MAX_THRESHOLD  Metric = "gpu_temperature_mem_max_threshold"
+	GPU_TEMPERATURE_GPU_MAX_THRESHOLD  Metric = "gpu_temperature_gpu_max_threshold"
+	GPU_TEMPERATURE_SHUTDOWN_HEADROOM  Metric = "gpu_temperature_shutdown_headroom"
+	GPU_TEMPERATURE_SLOWDOWN_HEADROOM  Metric = "gpu_temperature_slowdown_headroom"
+	GPU_MEMORY_TEMPERATURE             Metric = "gpu_memory_temperature"
+
+	GPU_FAN_TARGET_SPEED   Metric = "gpu_fan_target_speed"
+	GPU_FAN_CONTROL_POLICY Metric = "gpu_fan_control_policy"
+
+	GPU_RETIRED_PAGES             Metric = "gpu_retired_pages"
+	GPU_RETIRED_PAGES_PENDING     Metric = "gpu_retired_pages_pending"
+	GPU_REMAPPED_ROWS             Metric = "gpu_remapped_rows"
+	GPU_ROW_REMAP_PENDING         Metric = "gpu_row_remap_pending"
+	GPU_ROW_REMAP_FAILURE         Metric = "gpu_row_remap_failure"
+	GPU_ROW_REMAPPER_AVAILABILITY Metric = "gpu_row_remapper_availability"
+
+	GPU_ECC_ERRORS_TOTAL Metric = "gpu_ecc_errors_total"
+	GPU_ECC_MODE         Metric = "gpu_ecc_mode"
+	GPU_ECC_MODE_PENDING Metric = "gpu_ecc_mode_pending"
+
+	GPU_XID_ERRORS_TOTAL     Metric = "gpu_xid_errors_total"
+	GPU_EVENTS_TOTAL         Metric = "gpu_events_total"
+	GPU_LAST_EVENT_TIMESTAMP Metric = "gpu_last_event_timestamp_seconds"
+
+	GPU_MIG_MODE              Metric = "gpu_mig_mode"
+	GPU_MIG_GPU_INSTANCES     Metric = "gpu_mig_gpu_instances"
+	GPU_MIG_COMPUTE_INSTANCES Metric = "gpu_mig_compute_instances"
+	GPU_MIG_MEMORY_USED       Metric = "gpu_mig_memory_used_bytes"
+	GPU_MIG_MEMORY_TOTAL      Metric = "gpu_mig_memory_total_bytes"
+	GPU_MIG_RUNNING_PROCESS   Metric = "gpu_mig_running_process"
+
+	GPU_VGPU_INSTANCES        Metric = "gpu_vgpu_instances"
+	GPU_VGPU_FB_USED          Metric = "gpu_vgpu_fb_used_bytes"
+	GPU_VGPU_ENCODER_SESSIONS Metric = "gpu_vgpu_encoder_sessions"
+	GPU_VGPU_UTILIZATION      Metric = "gpu_vgpu_utilization"
+
+	GPU_MEMORY_RESERVED   Metric = "gpu_memory_reserved_bytes"
+	GPU_BAR1_MEMORY_USED  Metric = "gpu_bar1_memory_used_bytes"
+	GPU_BAR1_MEMORY_TOTAL Metric = "gpu_bar1_memory_total_bytes"
+	GPU_BAR1_MEMORY_FREE  Metric = "gpu_bar1_memory_free_bytes"
+
+	GPU_INFO Metric = "gpu_info"
+
+	GPU_GPU_UTILIZATION_SUMMARY Metric = "gpu_gpu_utilization_summary"
+	GPU_MEM_UTILIZATION_SUMMARY Metric = "gpu_mem_utilization_summary"
 )
 
 type Label string
@@ -45,6 +92,40 @@ const (
 	GPU_DRIVER_VERSION     Label = "gpu_driver_version"
 	GPU_CUDA_VERSION       Label = "gpu_cuda_version"
 	GPU_PEAK_FLOPS         Label = "gpu_peak_flops"
+
+	// Inventory labels for the gpu_info metric
+	GPU_UUID                    Label = "gpu_uuid"
+	GPU_SERIAL                  Label = "gpu_serial"
+	GPU_BOARD_PART_NUMBER       Label = "gpu_board_part_number"
+	GPU_VBIOS_VERSION           Label = "gpu_vbios_version"
+	GPU_INFOROM_IMAGE_VERSION   Label = "gpu_inforom_image_version"
+	GPU_INFOROM_OEM_VERSION     Label = "gpu_inforom_oem_version"
+	GPU_INFOROM_ECC_VERSION     Label = "gpu_inforom_ecc_version"
+	GPU_PCI_BUS_ID              Label = "gpu_pci_bus_id"
+	GPU_PCI_DEVICE_ID           Label = "gpu_pci_device_id"
+	GPU_PCI_SUBSYSTEM_ID        Label = "gpu_pci_subsystem_id"
+	GPU_ARCHITECTURE            Label = "gpu_architecture"
+	GPU_CUDA_COMPUTE_CAPABILITY Label = "gpu_cuda_compute_capability"
+	GPU_PERSISTENCE_MODE        Label = "gpu_persistence_mode"
+	GPU_COMPUTE_MODE            Label = "gpu_compute_mode"
+
+	// Labels set by the collector for each series instead of a label function
+	GPU_FAN                 Label = "fan"
+	GPU_CAUSE               Label = "cause"
+	GPU_REMAP_AVAILABILITY  Label = "availability"
+	GPU_ECC_ERROR_TYPE      Label = "error_type"
+	GPU_ECC_COUNTER_TYPE    Label = "counter_type"
+	GPU_MEMORY_LOCATION     Label = "location"
+	GPU_XID                 Label = "xid"
+	GPU_EVENT_TYPE          Label = "event_type"
+	GPU_MIG_PROFILE         Label = "mig_profile"
+	GPU_INSTANCE_ID         Label = "gpu_instance_id"
+	GPU_COMPUTE_INSTANCE_ID Label = "compute_instance_id"
+	GPU_VGPU_UUID           Label = "vgpu_uuid"
+	GPU_VM_ID               Label = "vm_id"
+	GPU_VGPU_TYPE           Label = "vgpu_type"
+	GPU_VGPU_ENGINE         Label = "engine"
+	GPU_STAT                Label = "stat"
 )
 
 func (m Metric) GetMetric() string {
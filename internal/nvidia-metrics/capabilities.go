@@ -0,0 +1,211 @@
+package nvidiametrics
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
+	"go.uber.org/zap"
+)
+
+var collectorCapabilities = NewCapabilities()
+
+// Capabilities records which collectors each device supports, keyed by the device UUID so a card
+// swapped into the same slot doesn't inherit the verdicts of the previous one.
+// A device is probed once when it shows up; a collector is disabled once a run returns not supported,
+// other errors may be transient and keep it enabled until a run settles it.
+type Capabilities struct {
+	mu        sync.Mutex
+	uuids     map[int]string             // device index to UUID, the last device set
+	probed    map[string]bool            // device UUIDs the probe pass ran for
+	supported map[string]map[string]bool // device UUID to collector, whether the collector is supported
+}
+
+// NewCapabilities creates an empty capability set, every device is probed when it shows up.
+func NewCapabilities() *Capabilities {
+	return &Capabilities{
+		uuids:     make(map[int]string),
+		probed:    make(map[string]bool),
+		supported: make(map[string]map[string]bool),
+	}
+}
+
+// Enabled reports whether the collector should run on the device, false once it returned not supported.
+func (c *Capabilities) Enabled(uuid string, collector string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	supported, probed := c.supported[uuid][collector]
+	return !probed || supported
+}
+
+// Record records the result of a run of the collector on the device until one is definitive,
+// success marks the collector supported and not supported disables it. Any other error may be transient,
+// e.g. a timeout, the collector stays enabled and is probed again on its next run.
+// It returns true when the run settled the capability, later results don't change it.
+func (c *Capabilities) Record(uuid string, collector string, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, probed := c.supported[uuid][collector]; probed {
+		return false
+	}
+
+	if err != nil && !nvmlerrors.IsNotSupported(err) {
+		return false
+	}
+
+	device, ok := c.supported[uuid]
+	if !ok {
+		device = make(map[string]bool)
+		c.supported[uuid] = device
+	}
+
+	device[collector] = err == nil
+	return true
+}
+
+// Supported returns the collectors with a definitive probe of the device and whether they are supported.
+func (c *Capabilities) Supported(uuid string) map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	supported := make(map[string]bool, len(c.supported[uuid]))
+	for collector, ok := range c.supported[uuid] {
+		supported[collector] = ok
+	}
+	return supported
+}
+
+// StartProbe reports whether the device still needs the probe pass and marks it as probed.
+func (c *Capabilities) StartProbe(uuid string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.probed[uuid] {
+		return false
+	}
+	c.probed[uuid] = true
+	return true
+}
+
+// Refresh records the device set and forgets the capabilities of devices that are gone.
+// It returns the device indexes that now hold another GPU or are gone.
+func (c *Capabilities) Refresh(uuids map[int]string) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reset []int
+	for deviceIndex, uuid := range c.uuids {
+		if uuids[deviceIndex] != uuid {
+			reset = append(reset, deviceIndex)
+		}
+	}
+
+	present := make(map[string]bool, len(uuids))
+	c.uuids = make(map[int]string, len(uuids))
+	for deviceIndex, uuid := range uuids {
+		c.uuids[deviceIndex] = uuid
+		present[uuid] = true
+	}
+
+	for uuid := range c.supported {
+		if !present[uuid] {
+			delete(c.supported, uuid)
+		}
+	}
+	for uuid := range c.probed {
+		if !present[uuid] {
+			delete(c.probed, uuid)
+		}
+	}
+	return reset
+}
+
+// collectorEnabled reports whether the collector should run on the device.
+func collectorEnabled(uuid string, collector string) bool {
+	return collectorCapabilities.Enabled(uuid, collector)
+}
+
+// recordCapability records the run of the collector on the device, logging once when it is disabled.
+// collector_supported is only changed once the run is definitive, the probe pass sets it first.
+func recordCapability(deviceIndex int, uuid string, collector string, err error) {
+	if !collectorCapabilities.Record(uuid, collector, err) {
+		return
+	}
+
+	if nvmlerrors.IsNotSupported(err) {
+		logger.Info("Collector not supported by the device, disabling it",
+			zap.Int("device_index", deviceIndex),
+			zap.String("collector", collector),
+			zap.Error(err),
+		)
+	}
+	setCollectorSupported(deviceIndex, collector, err)
+}
+
+// setCollectorSupported sets collector_supported of the device, 0 when the collector returned not supported.
+func setCollectorSupported(deviceIndex int, collector string, err error) {
+	supported := 1.0
+	if nvmlerrors.IsNotSupported(err) {
+		supported = 0
+	}
+	collectorSupported.WithLabelValues(strconv.Itoa(deviceIndex), collector).Set(supported)
+}
+
+// refreshCapabilities records the device set and deletes the collector_supported series of replaced devices.
+// It returns the device indexes that were replaced or are gone.
+func refreshCapabilities(uuids map[int]string) []int {
+	reset := collectorCapabilities.Refresh(uuids)
+	for _, deviceIndex := range reset {
+		collectorSupported.DeletePartialMatch(map[string]string{"device": strconv.Itoa(deviceIndex)})
+	}
+	return reset
+}
+
+// probeCapabilities runs the probe pass for the devices that weren't probed yet, at startup and when a GPU
+// was added or replaced, so collector_supported lists every collector without waiting for their intervals.
+func probeCapabilities(ctx context.Context, uuids map[int]string) {
+	for deviceIndex, uuid := range uuids {
+		if !collectorCapabilities.StartProbe(uuid) {
+			continue
+		}
+
+		handle, ret := nvml.DeviceGetHandleByIndex(deviceIndex)
+		if ret != nvml.SUCCESS {
+			logger.Error("Error getting device handle for the capability probe",
+				zap.Int("device_index", deviceIndex), zap.Error(nvmlerrors.New(ret, "get device handle")))
+			continue
+		}
+		probeDevice(ctx, deviceIndex, uuid, handle)
+	}
+}
+
+// probeDevice runs every configured collector once on the device and sets collector_supported for each.
+func probeDevice(ctx context.Context, deviceIndex int, uuid string, handle nvml.Device) {
+	metrics := NewGPUDeviceMetrics()
+	metrics.DeviceIndex = deviceIndex
+	metrics.DeviceUUID = uuid
+
+	probed := 0
+	for _, collector := range deviceCollectors {
+		if !anyRegistered(collector.metrics...) {
+			continue
+		}
+
+		err := observeCollector(deviceIndex, uuid, collector.name, func() error {
+			return collector.collect(ctx, metrics, handle)
+		})
+		setCollectorSupported(deviceIndex, collector.name, err)
+		probed++
+	}
+
+	logger.Info("Probed the collectors of the device",
+		zap.Int("device_index", deviceIndex),
+		zap.String("uuid", uuid),
+		zap.Int("collectors", probed),
+	)
+}
@@ -0,0 +1,135 @@
+package nvidiametrics
+
+import (
+	"context"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
+)
+
+var _ = Describe("Capabilities", func() {
+	var capabilities *Capabilities
+
+	BeforeEach(func() {
+		capabilities = NewCapabilities()
+		capabilities.Refresh(map[int]string{0: "GPU-0", 1: "GPU-1"})
+	})
+
+	It("should enable collectors until they are probed", func() {
+		Expect(capabilities.Enabled("GPU-0", "fan_speed")).To(BeTrue())
+	})
+
+	It("should disable collectors the device doesn't support", func() {
+		Expect(capabilities.Record("GPU-0", "fan_speed", nvmlerrors.New(nvml.ERROR_NOT_SUPPORTED, "fan_speed"))).To(BeTrue())
+
+		Expect(capabilities.Enabled("GPU-0", "fan_speed")).To(BeFalse())
+		Expect(capabilities.Enabled("GPU-1", "fan_speed")).To(BeTrue())
+		Expect(capabilities.Supported("GPU-0")).To(Equal(map[string]bool{"fan_speed": false}))
+	})
+
+	It("should keep probing collectors on other errors", func() {
+		Expect(capabilities.Record("GPU-0", "power_info", nvmlerrors.New(nvml.ERROR_TIMEOUT, "power_info"))).To(BeFalse())
+
+		Expect(capabilities.Enabled("GPU-0", "power_info")).To(BeTrue())
+		Expect(capabilities.Supported("GPU-0")).To(BeEmpty())
+
+		Expect(capabilities.Record("GPU-0", "power_info", nvmlerrors.New(nvml.ERROR_NOT_SUPPORTED, "power_info"))).To(BeTrue())
+		Expect(capabilities.Enabled("GPU-0", "power_info")).To(BeFalse())
+	})
+
+	It("should only record the first run", func() {
+		Expect(capabilities.Record("GPU-0", "ecc_mode", nil)).To(BeTrue())
+		Expect(capabilities.Record("GPU-0", "ecc_mode", nvmlerrors.New(nvml.ERROR_NOT_SUPPORTED, "ecc_mode"))).To(BeFalse())
+
+		Expect(capabilities.Enabled("GPU-0", "ecc_mode")).To(BeTrue())
+	})
+
+	It("should not carry the capabilities over to a card swapped into the slot", func() {
+		capabilities.Record("GPU-1", "fan_speed", nvmlerrors.New(nvml.ERROR_NOT_SUPPORTED, "fan_speed"))
+
+		reset := capabilities.Refresh(map[int]string{0: "GPU-0", 1: "GPU-2"})
+
+		Expect(reset).To(Equal([]int{1}))
+		Expect(capabilities.Enabled("GPU-2", "fan_speed")).To(BeTrue())
+		Expect(capabilities.Supported("GPU-1")).To(BeEmpty())
+	})
+
+	It("should report removed devices", func() {
+		reset := capabilities.Refresh(map[int]string{0: "GPU-0"})
+
+		Expect(reset).To(Equal([]int{1}))
+	})
+
+	It("should probe a device once", func() {
+		Expect(capabilities.StartProbe("GPU-0")).To(BeTrue())
+		Expect(capabilities.StartProbe("GPU-0")).To(BeFalse())
+
+		capabilities.Refresh(map[int]string{1: "GPU-1"})
+		capabilities.Refresh(map[int]string{0: "GPU-0", 1: "GPU-1"})
+		Expect(capabilities.StartProbe("GPU-0")).To(BeTrue())
+	})
+
+	It("should skip an unsupported collector after the probe", func() {
+		collectorCapabilities = NewCapabilities()
+		DeferCleanup(func() { collectorCapabilities = NewCapabilities() })
+
+		runs := 0
+		collect := func() error {
+			runs++
+			return nvmlerrors.New(nvml.ERROR_NOT_SUPPORTED, "fan_speed")
+		}
+
+		Expect(nvmlerrors.IsNotSupported(observeCollector(3, "GPU-3", "fan_speed", collect))).To(BeTrue())
+		Expect(observeCollector(3, "GPU-3", "fan_speed", collect)).To(Succeed())
+
+		Expect(runs).To(Equal(1))
+		Expect(testutil.ToFloat64(collectorSupported.WithLabelValues("3", "fan_speed"))).To(Equal(0.0))
+	})
+
+	Context("probeDevice", func() {
+		BeforeEach(func() {
+			collectorCapabilities = NewCapabilities()
+			DeferCleanup(func() { collectorCapabilities = NewCapabilities() })
+
+			savedCollectors := deviceCollectors
+			DeferCleanup(func() { deviceCollectors = savedCollectors })
+		})
+
+		It("should set collector_supported for every configured collector", func() {
+			registerTestGauge(config.GPU_FAN_SPEED)
+			registerTestGauge(config.GPU_POWER_USAGE)
+			registerTestGauge(config.GPU_P_STATE)
+
+			runs := 0
+			collector := func(name string, metric config.Metric, ret nvml.Return) deviceCollector {
+				return deviceCollector{
+					name:    name,
+					metrics: []config.Metric{metric},
+					collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+						runs++
+						return WithContext(ctx, name, func() nvml.Return { return ret })
+					},
+				}
+			}
+			deviceCollectors = []deviceCollector{
+				collector("probe_fan_speed", config.GPU_FAN_SPEED, nvml.ERROR_NOT_SUPPORTED),
+				collector("probe_power_info", config.GPU_POWER_USAGE, nvml.SUCCESS),
+				collector("probe_p_state", config.GPU_P_STATE, nvml.ERROR_TIMEOUT),
+				collector("probe_vgpu", config.GPU_VGPU_INSTANCES, nvml.SUCCESS),
+			}
+
+			probeDevice(ctx, 6, "GPU-6", new(MockNvmlDevice))
+
+			Expect(runs).To(Equal(3), "collectors of metrics that aren't configured are not probed")
+			Expect(testutil.ToFloat64(collectorSupported.WithLabelValues("6", "probe_fan_speed"))).To(Equal(0.0))
+			Expect(testutil.ToFloat64(collectorSupported.WithLabelValues("6", "probe_power_info"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(collectorSupported.WithLabelValues("6", "probe_p_state"))).To(Equal(1.0))
+			Expect(collectorCapabilities.Enabled("GPU-6", "probe_fan_speed")).To(BeFalse())
+			Expect(collectorCapabilities.Enabled("GPU-6", "probe_p_state")).To(BeTrue())
+		})
+	})
+})
@@ -2,9 +2,12 @@ package nvidiametrics
 
 import (
 	"fmt"
+	"sync"
+
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
 	"go.uber.org/zap"
 )
 
@@ -34,15 +37,11 @@ func (lf LabelFunctions) Add(labelName string, f DeviceInfo) {
 }
 
 func (lf LabelFunctions) GetLabelFunc(labelName string) (func(device nvml.Device) (any, nvml.Return), error) {
-	if lf == nil {
-		return nil, fmt.Errorf("label function map empty") // TODO: return error)
-	}
-
 	if f, ok := (lf)[labelName]; ok {
 		logger.Debug("Label function found", zap.String("label_name", labelName))
 		return f.GetFunction(), nil
 	}
-	return nil, fmt.Errorf("label function not found for label %s", labelName)
+	return nil, nvmlerrors.LabelNotRegistered(labelName)
 }
 
 func (lf LabelFunctions) SetLabelFunc(labelName string, f DeviceInfo) {
@@ -74,37 +73,135 @@ func GetLabelKeys(metricName string) map[string]string {
 }
 
 // FetchDeviceLabelValue fetches the label value for the given device and label name
-func (lf LabelFunctions) FetchDeviceLabelValue(device nvml.Device, labelName string) any {
+// The error is a nvmlerrors error, ErrLabelNotRegistered if there is no label function for the label.
+func (lf LabelFunctions) FetchDeviceLabelValue(device nvml.Device, labelName string) (any, error) {
 
 	labelFunc, err := lf.GetLabelFunc(labelName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	value, ret := labelFunc(device)
 	if ret != nvml.SUCCESS {
-		logger.Error("Error fetching label value", zap.String("label_name", labelName))
-		return nil
+		return nil, nvmlerrors.New(ret, labelName)
 	}
-	return value
+	return value, nil
 
 }
 
-// GetLabelValue returns the label value for the given device and label name
+// GetLabelValue returns the label value for the given device and label name, empty if it can't be fetched.
+// Static label values are served from the label cache once fetched for the device.
 func (lf LabelFunctions) GetLabelValue(device nvml.Device, labelName string) string {
+	uuid, ok := labelCache.UUID(device)
+	if ok && staticLabels[labelName] {
+		if value, ok := labelCache.Get(uuid, labelName); ok {
+			return value
+		}
+	}
+
 	// get the label value
-	value := lf.FetchDeviceLabelValue(device, labelName)
-	return fmt.Sprintf("%v", value)
+	value, err := lf.FetchDeviceLabelValue(device, labelName)
+	if err != nil {
+		// don't cache failures, fetch again on the next set
+		logger.Error("Error fetching label value", zap.String("label_name", labelName), zap.Error(err))
+		return ""
+	}
+
+	labelValue := fmt.Sprintf("%v", value)
+	if ok && staticLabels[labelName] {
+		labelCache.Set(uuid, labelName, labelValue)
+	}
+	return labelValue
 }
 
 // GetMetricLabelValues returns all the label values for the given device and metric name
 func (lf LabelFunctions) GetMetricLabelValues(device nvml.Device, metricName string) map[string]string {
+	return lf.GetMetricLabelValuesWith(device, metricName, nil)
+}
+
+// GetMetricLabelValuesWith returns all the label values for the given device and metric name,
+// labels present in extraLabels are taken as is instead of being fetched from the device.
+// Example: extraLabels {"fan": "0"} for metrics reported once per fan
+func (lf LabelFunctions) GetMetricLabelValuesWith(device nvml.Device, metricName string, extraLabels map[string]string) map[string]string {
 	labelValues := GetLabelKeys(metricName)
 
 	// iterate over the label functions and get the label values
 	for labelName := range labelValues {
+		if value, ok := extraLabels[labelName]; ok {
+			labelValues[labelName] = value
+			continue
+		}
 		labelValues[labelName] = lf.GetLabelValue(device, labelName)
 	}
 
 	return labelValues
 }
+
+// LabelCache caches the static label values per device UUID.
+type LabelCache struct {
+	mu     sync.RWMutex
+	uuids  map[nvml.Device]string       // device handle to UUID for the current device set
+	values map[string]map[string]string // device UUID to static label values
+}
+
+func NewLabelCache() *LabelCache {
+	return &LabelCache{
+		uuids:  make(map[nvml.Device]string),
+		values: make(map[string]map[string]string),
+	}
+}
+
+// Refresh records the current device set, the cached values are dropped when the set of UUIDs changed,
+// e.g. after a GPU was added, removed or reset and the device indexes moved.
+func (c *LabelCache) Refresh(uuids map[nvml.Device]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !sameDeviceSet(c.uuids, uuids) {
+		logger.Info("GPU device set changed, clearing cached label values", zap.Int("devices", len(uuids)))
+		c.values = make(map[string]map[string]string)
+	}
+	c.uuids = uuids
+}
+
+// UUID returns the UUID recorded for the device handle in the current device set.
+func (c *LabelCache) UUID(device nvml.Device) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	uuid, ok := c.uuids[device]
+	return uuid, ok
+}
+
+// Get returns the cached value of a static label for the device UUID.
+func (c *LabelCache) Get(uuid string, labelName string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	value, ok := c.values[uuid][labelName]
+	return value, ok
+}
+
+// Set caches the value of a static label for the device UUID.
+func (c *LabelCache) Set(uuid string, labelName string, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.values[uuid]; !ok {
+		c.values[uuid] = make(map[string]string)
+	}
+	c.values[uuid][labelName] = value
+}
+
+// sameDeviceSet reports whether both maps hold the same devices with the same UUIDs.
+func sameDeviceSet(a, b map[nvml.Device]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for device, uuid := range a {
+		if b[device] != uuid {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package nvidiametrics
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+)
+
+var _ = Describe("LabelCache", func() {
+	var (
+		labelFunctions LabelFunctions
+		mockHandle     *MockNvmlDevice
+		calls          int
+	)
+
+	BeforeEach(func() {
+		calls = 0
+		labelFunctions = NewLabelFunction()
+		labelFunctions.Add(config.GPU_NAME.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+			calls++
+			return "NVIDIA GeForce RTX 3060", nvml.SUCCESS
+		})
+		labelFunctions.Add(config.GPU_COMPUTE_MODE.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+			calls++
+			return "default", nvml.SUCCESS
+		})
+
+		mockHandle = new(MockNvmlDevice)
+		savedLabelCache := labelCache
+		DeferCleanup(func() { labelCache = savedLabelCache })
+		labelCache = NewLabelCache()
+		labelCache.Refresh(map[nvml.Device]string{mockHandle: "GPU-1234"})
+	})
+
+	It("should fetch static labels once per device", func() {
+		Expect(labelFunctions.GetLabelValue(mockHandle, config.GPU_NAME.GetLabel())).To(Equal("NVIDIA GeForce RTX 3060"))
+		Expect(labelFunctions.GetLabelValue(mockHandle, config.GPU_NAME.GetLabel())).To(Equal("NVIDIA GeForce RTX 3060"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should fetch dynamic labels on every call", func() {
+		labelFunctions.GetLabelValue(mockHandle, config.GPU_COMPUTE_MODE.GetLabel())
+		labelFunctions.GetLabelValue(mockHandle, config.GPU_COMPUTE_MODE.GetLabel())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("should fetch static labels again after the device set changed", func() {
+		labelFunctions.GetLabelValue(mockHandle, config.GPU_NAME.GetLabel())
+
+		labelCache.Refresh(map[nvml.Device]string{mockHandle: "GPU-5678"})
+		labelFunctions.GetLabelValue(mockHandle, config.GPU_NAME.GetLabel())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("should keep the cache when the device set is unchanged", func() {
+		labelFunctions.GetLabelValue(mockHandle, config.GPU_NAME.GetLabel())
+
+		labelCache.Refresh(map[nvml.Device]string{mockHandle: "GPU-1234"})
+		labelFunctions.GetLabelValue(mockHandle, config.GPU_NAME.GetLabel())
+		Expect(calls).To(Equal(1))
+	})
+})
@@ -3,26 +3,44 @@ package nvidiametrics
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
 	"go.uber.org/zap"
 )
 
 var labelManager = NewLabelFunction()
+var labelCache = NewLabelCache()
+
+// addLabelFunctionsOnce guards the label function map, the event monitor reads it concurrently with the collection loop.
+var addLabelFunctionsOnce sync.Once
 
 // CollectGpuMetrics collects metrics for all the GPUs.
 func CollectGpuMetrics(ctx context.Context) {
 	deviceCount, err := CollectGPUDeviceCount(ctx)
 	if err != nil || deviceCount == 0 {
 		logger.Error("No GPU devices found", zap.Error(err))
+		collectionStatus.collected(0, "no GPU devices found", time.Now())
 		return
 	}
 
 	// Add label functions
-	labelManager.AddFunctions()
+	addLabelFunctionsOnce.Do(labelManager.AddFunctions)
+
+	// Record the device set for the label cache, at the default interval instead of every tick
+	// and probe the collectors of devices that showed up since
+	if collectionScheduler.DeviceSetDue(time.Now(), deviceCount) {
+		probeCapabilities(ctx, refreshLabelCache(deviceCount))
+	}
 
+	failed := 0
 	for i := 0; i < deviceCount; i++ {
 		metrics, err := collectDeviceMetrics(ctx, i)
 		if err != nil {
@@ -31,28 +49,263 @@ func CollectGpuMetrics(ctx context.Context) {
 				zap.Int("gpu_index", i),
 				zap.Error(err),
 			)
+			failed++
 			continue // Skip this GPU and proceed with the next one
 		}
-		// Use the collected metrics if needed
-		// To Replace this with actual usage.
-		// @TODO add this to slice of metrics for cli client
-		_ = metrics
+
+		// Keep the device state for the JSON api
+		snapshots.SetCollected(metrics, time.Now())
+	}
+
+	if failed > 0 {
+		collectionStatus.collected(deviceCount, fmt.Sprintf("failed to collect metrics for %d of %d GPUs", failed, deviceCount), time.Now())
+		return
 	}
 
 	// Here we have successfully collected metrics for all GPUs without errors.
-	logger.Info("Successfully collected metrics for all GPUs")
+	now := time.Now()
+	collectionStatus.collected(deviceCount, "", now)
+	lastSuccessfulCollection.Set(float64(now.Unix()))
+	logger.Debug("Successfully collected metrics for all GPUs")
+}
+
+// deviceCollector collects a group of device metrics, it runs when one of its metrics is due.
+type deviceCollector struct {
+	name    string          // label of the collector in the exporter metrics and capabilities
+	message string          // logged when the collector fails
+	metrics []config.Metric // the metrics the collector sets
+	collect func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error
+}
+
+// deviceCollectors are the collectors run for every device, in order.
+var deviceCollectors = append(append([]deviceCollector{
+	{
+		name:    "temperature",
+		message: "Error collecting temperature metrics",
+		metrics: []config.Metric{config.GPU_TEMPERATURE},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.CollectTemperatureMetrics(ctx, handle, config.GPU_TEMPERATURE)
+		},
+	},
+	{
+		name:    "memory_temperature",
+		message: "Error collecting memory temperature metrics",
+		metrics: []config.Metric{config.GPU_MEMORY_TEMPERATURE},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectMemoryTemperatureMetrics(ctx, handle, config.GPU_MEMORY_TEMPERATURE)
+		},
+	},
+	{
+		name:    "utilization",
+		message: "Error collecting utilization metrics",
+		metrics: []config.Metric{config.GPU_GPU_UTILIZATION, config.GPU_MEM_UTILIZATION},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.CollectUtilizationMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "utilization_samples",
+		message: "Error collecting utilization samples metrics",
+		metrics: []config.Metric{config.GPU_GPU_UTILIZATION_SUMMARY, config.GPU_MEM_UTILIZATION_SUMMARY},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectUtilizationSamplesMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "memory_info",
+		message: "Error collecting memory info metrics",
+		metrics: []config.Metric{config.GPU_MEMORY_USED, config.GPU_MEMORY_TOTAL, config.GPU_MEMORY_FREE, config.GPU_MEMORY_RESERVED},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.CollectMemoryInfoMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "bar1_memory",
+		message: "Error collecting BAR1 memory metrics",
+		metrics: []config.Metric{config.GPU_BAR1_MEMORY_USED, config.GPU_BAR1_MEMORY_TOTAL, config.GPU_BAR1_MEMORY_FREE},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectBar1MemoryMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "power_info",
+		message: "Error collecting power info metrics",
+		metrics: []config.Metric{config.GPU_POWER_USAGE},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.CollectPowerInfoMetrics(ctx, handle, config.GPU_POWER_USAGE)
+		},
+	},
+	{
+		name:    "running_process",
+		message: "Error collecting running process metrics",
+		metrics: []config.Metric{config.GPU_RUNNING_PROCESS},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.CollectRunningProcessMetrics(ctx, handle, config.GPU_RUNNING_PROCESS)
+		},
+	},
+	{
+		name:    "device_info",
+		message: "Error collecting device info metrics",
+		metrics: []config.Metric{config.GPU_INFO},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectDeviceInfoMetrics(ctx, handle, config.GPU_INFO)
+		},
+	},
+	{
+		name:    "p_state",
+		message: "Error collecting p state metrics",
+		metrics: []config.Metric{config.GPU_P_STATE},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectPStateMetrics(ctx, handle, config.GPU_P_STATE)
+		},
+	},
+	{
+		name:    "ecc_corrected_errors",
+		message: "Error collecting ECC corrected errors metrics",
+		metrics: []config.Metric{config.GPU_ECC_CORRECTED_ERRORS},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectEccCorrectedErrorsMetrics(ctx, handle, config.GPU_ECC_CORRECTED_ERRORS)
+		},
+	},
+	{
+		name:    "ecc_uncorrected_errors",
+		message: "Error collecting ECC uncorrected errors metrics",
+		metrics: []config.Metric{config.GPU_ECC_UNCORRECTED_ERRORS},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectEccUncorrectedErrorsMetrics(ctx, handle, config.GPU_ECC_UNCORRECTED_ERRORS)
+		},
+	},
+	{
+		name:    "ecc_mode",
+		message: "Error collecting ECC mode metrics",
+		metrics: []config.Metric{config.GPU_ECC_MODE, config.GPU_ECC_MODE_PENDING},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectEccModeMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "ecc_location_errors",
+		message: "Error collecting ECC errors by location metrics",
+		metrics: []config.Metric{config.GPU_ECC_ERRORS_TOTAL},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectEccLocationErrorsMetrics(ctx, handle, config.GPU_ECC_ERRORS_TOTAL)
+		},
+	},
+	{
+		name:    "sm_clock",
+		message: "Error collecting GPU clock metrics",
+		metrics: []config.Metric{config.GPU_SM_CLOCK},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectGpuClockMetrics(ctx, handle, config.GPU_SM_CLOCK)
+		},
+	},
+	{
+		name:    "graphics_clock",
+		message: "Error collecting GPU graphics clock metrics",
+		metrics: []config.Metric{config.GPU_GRAPHICS_CLOCK},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectGpuGraphicsClockMetrics(ctx, handle, config.GPU_GRAPHICS_CLOCK)
+		},
+	},
+	{
+		name:    "video_clock",
+		message: "Error collecting GPU video clock metrics",
+		metrics: []config.Metric{config.GPU_VIDEO_CLOCK},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectGpuVideoClockMetrics(ctx, handle, config.GPU_VIDEO_CLOCK)
+		},
+	},
+	{
+		name:    "memory_clock",
+		message: "Error collecting memory clock metrics",
+		metrics: []config.Metric{config.GPU_MEMORY_CLOCK},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectMemoryClockMetrics(ctx, handle, config.GPU_MEMORY_CLOCK)
+		},
+	},
+	{
+		name:    "peak_flops",
+		message: "Error collecting peak flops metrics",
+		metrics: []config.Metric{config.GPU_PEAK_FLOPS_METRIC},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectPeakFlopsMetrics(ctx, handle, config.GPU_PEAK_FLOPS_METRIC)
+		},
+	},
+	{
+		name:    "fan_speed",
+		message: "Error collecting fan speed metrics",
+		metrics: []config.Metric{config.GPU_FAN_SPEED, config.GPU_FAN_TARGET_SPEED, config.GPU_FAN_CONTROL_POLICY},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectFanSpeedMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "retired_pages",
+		message: "Error collecting retired pages metrics",
+		metrics: []config.Metric{config.GPU_RETIRED_PAGES, config.GPU_RETIRED_PAGES_PENDING},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectRetiredPagesMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "remapped_rows",
+		message: "Error collecting remapped rows metrics",
+		metrics: []config.Metric{config.GPU_REMAPPED_ROWS, config.GPU_ROW_REMAP_PENDING, config.GPU_ROW_REMAP_FAILURE, config.GPU_ROW_REMAPPER_AVAILABILITY},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectRemappedRowsMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "mig",
+		message: "Error collecting MIG metrics",
+		metrics: []config.Metric{config.GPU_MIG_MODE, config.GPU_MIG_GPU_INSTANCES, config.GPU_MIG_COMPUTE_INSTANCES, config.GPU_MIG_MEMORY_USED, config.GPU_MIG_MEMORY_TOTAL, config.GPU_MIG_RUNNING_PROCESS},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectMigMetrics(ctx, handle)
+		},
+	},
+	{
+		name:    "vgpu",
+		message: "Error collecting vGPU metrics",
+		metrics: []config.Metric{config.GPU_VGPU_INSTANCES, config.GPU_VGPU_FB_USED, config.GPU_VGPU_ENCODER_SESSIONS, config.GPU_VGPU_UTILIZATION},
+		collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+			return metrics.collectVgpuMetrics(ctx, handle)
+		},
+	},
+}, thresholdCollectors(temperatureThresholds, "Error collecting temperature threshold metrics",
+	(*GPUDeviceMetrics).collectTemperatureThresholdMetrics)...),
+	thresholdCollectors(temperatureHeadroom, "Error collecting temperature headroom metrics",
+		(*GPUDeviceMetrics).collectTemperatureHeadroomMetrics)...)
+
+// thresholdCollectors creates a collector per temperature threshold metric, named after the metric.
+func thresholdCollectors(thresholds map[config.Metric]nvml.TemperatureThresholds, message string,
+	collect func(*GPUDeviceMetrics, context.Context, nvml.Device, config.Metric, nvml.TemperatureThresholds) error) []deviceCollector {
+	collectors := make([]deviceCollector, 0, len(thresholds))
+	for metric, threshold := range thresholds {
+		collectors = append(collectors, deviceCollector{
+			name:    metric.GetMetric(),
+			message: message,
+			metrics: []config.Metric{metric},
+			collect: func(ctx context.Context, metrics *GPUDeviceMetrics, handle nvml.Device) error {
+				return collect(metrics, ctx, handle, metric, threshold)
+			},
+		})
+	}
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name < collectors[j].name })
+	return collectors
 }
 
 // CollectGpuDeviceMetrics collects metrics for a single device and returns them in a GPUDeviceMetrics struct.
 func collectDeviceMetrics(ctx context.Context, deviceIndex int) (*GPUDeviceMetrics, error) {
-	handle, err := nvml.DeviceGetHandleByIndex(deviceIndex)
-	if err != nvml.SUCCESS {
+	handle, ret := nvml.DeviceGetHandleByIndex(deviceIndex)
+	if ret != nvml.SUCCESS {
+		err := nvmlerrors.New(ret, "get device handle")
 		logger.Error("Error getting device handle", zap.Int("device_index", deviceIndex), zap.Error(err))
 		return nil, err
 	}
 
-	deviceName, err := handle.GetName()
-	if err != nvml.SUCCESS {
+	deviceName, ret := handle.GetName()
+	if ret != nvml.SUCCESS {
+		err := nvmlerrors.New(ret, "get device name")
 		logger.Error("Error getting device name", zap.Error(err))
 		return nil, err
 	}
@@ -65,112 +318,86 @@ func collectDeviceMetrics(ctx context.Context, deviceIndex int) (*GPUDeviceMetri
 
 	metrics := NewGPUDeviceMetrics()
 	metrics.DeviceIndex = deviceIndex
-
-	// Collect Device Metrics
-	if isRegistered(config.GPU_TEMPERATURE) {
-		err = metrics.CollectTemperatureMetrics(ctx, handle, config.GPU_TEMPERATURE)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting temperature metrics", zap.Error(err))
-		}
-	}
-
-	err = metrics.CollectUtilizationMetrics(ctx, handle)
-	if err != nvml.SUCCESS {
-		logger.Error("Error collecting utilization metrics", zap.Error(err))
-	}
-
-	err = metrics.CollectMemoryInfoMetrics(ctx, handle)
-	if err != nvml.SUCCESS {
-		logger.Error("Error collecting memory info metrics", zap.Error(err))
+	metrics.DeviceName = deviceName
+	// read the UUID on every collection, a card swapped in since the device set was read has its own capabilities
+	metrics.DeviceUUID, ret = handle.GetUUID()
+	if ret != nvml.SUCCESS {
+		err := nvmlerrors.New(ret, "get device uuid")
+		logger.Error("Error getting device UUID", zap.Int("device_index", deviceIndex), zap.Error(err))
+		return nil, err
 	}
 
-	if isRegistered(config.GPU_POWER_USAGE) {
-		err = metrics.CollectPowerInfoMetrics(ctx, handle, config.GPU_POWER_USAGE)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting power info metrics", zap.Error(err))
+	for _, collector := range deviceCollectors {
+		if !isDue(deviceIndex, collector.metrics...) {
+			continue
 		}
-	}
 
-	if isRegistered(config.GPU_RUNNING_PROCESS) {
-		err = metrics.CollectRunningProcessMetrics(ctx, handle, config.GPU_RUNNING_PROCESS)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting running process metrics", zap.Error(err))
+		err := observeCollector(deviceIndex, metrics.DeviceUUID, collector.name, func() error {
+			return collector.collect(ctx, metrics, handle)
+		})
+		if err != nil {
+			logCollectorError(collector.message, err, zap.String("collector", collector.name))
 		}
 	}
 
-	if isRegistered(config.GPU_ID_METRIC) {
-		err = metrics.CollectDeviceIdAsMetric(ctx, handle, config.GPU_ID_METRIC)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting device id as metric", zap.Error(err))
-		}
-	}
+	// @TODO Add more metrics here.
 
-	if isRegistered(config.GPU_P_STATE) {
-		err = metrics.collectPStateMetrics(ctx, handle, config.GPU_P_STATE)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting p state metrics", zap.Error(err))
-		}
-	}
+	logger.Debug("Collected GPU metrics", zap.Int("device_index", deviceIndex))
+	return metrics, nil
+}
 
-	if isRegistered(config.GPU_ECC_CORRECTED_ERRORS) {
-		err = metrics.collectEccCorrectedErrorsMetrics(ctx, handle, config.GPU_ECC_CORRECTED_ERRORS)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting ECC corrected errors metrics", zap.Error(err))
-		}
+// logCollectorError logs the collector error, unsupported collectors are expected on many GPUs and only logged at debug.
+func logCollectorError(message string, err error, fields ...zap.Field) {
+	fields = append(fields, zap.Error(err))
+	if nvmlerrors.IsNotSupported(err) {
+		logger.Debug(message, fields...)
+		return
 	}
+	logger.Error(message, fields...)
+}
 
-	if isRegistered(config.GPU_ECC_UNCORRECTED_ERRORS) {
-		err = metrics.collectEccUncorrectedErrorsMetrics(ctx, handle, config.GPU_ECC_UNCORRECTED_ERRORS)
+// refreshLabelCache records the UUID of every device so static label values are cached per device,
+// and deletes the series of replaced devices. It returns the UUIDs by device index.
+func refreshLabelCache(deviceCount int) map[int]string {
+	uuids := make(map[nvml.Device]string, deviceCount)
+	indexes := make(map[int]string, deviceCount)
+	for i := 0; i < deviceCount; i++ {
+		handle, err := nvml.DeviceGetHandleByIndex(i)
 		if err != nvml.SUCCESS {
-			logger.Error("Error collecting ECC uncorrected errors metrics", zap.Error(err))
+			continue
 		}
-	}
 
-	if isRegistered(config.GPU_SM_CLOCK) {
-		err = metrics.collectGpuClockMetrics(ctx, handle, config.GPU_SM_CLOCK)
+		uuid, err := handle.GetUUID()
 		if err != nvml.SUCCESS {
-			logger.Error("Error collecting GPU clock metrics", zap.Error(err))
+			continue
 		}
+		uuids[handle] = uuid
+		indexes[i] = uuid
 	}
 
-	if isRegistered(config.GPU_GRAPHICS_CLOCK) {
-		err = metrics.collectGpuGraphicsClockMetrics(ctx, handle, config.GPU_GRAPHICS_CLOCK)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting GPU graphics clock metrics", zap.Error(err))
-		}
-	}
+	labelCache.Refresh(uuids)
 
-	if isRegistered(config.GPU_VIDEO_CLOCK) {
-		err = metrics.collectGpuVideoClockMetrics(ctx, handle, config.GPU_VIDEO_CLOCK)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting GPU video clock metrics", zap.Error(err))
-		}
+	// the running totals of a replaced device don't continue the counters of the old one,
+	// and its inventory isn't reported for the new one
+	reset := refreshCapabilities(indexes)
+	for _, deviceIndex := range reset {
+		prometheusmetrics.DeleteCounterSeries(prometheusmetrics.GpuLabels{config.GPU_ID.GetLabel(): strconv.Itoa(deviceIndex)})
+		forgetDeviceInfo(deviceIndex)
 	}
 
-	if isRegistered(config.GPU_MEMORY_CLOCK) {
-		err = metrics.collectMemoryClockMetrics(ctx, handle, config.GPU_MEMORY_CLOCK)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting memory clock metrics", zap.Error(err))
-		}
+	// the sample timestamps of a removed or replaced device don't apply to the device now at its index
+	if len(reset) > 0 {
+		lastSamples.Reset()
 	}
 
-	if isRegistered(config.GPU_PEAK_FLOPS_METRIC) {
-		err = metrics.collectPeakFlopsMetrics(ctx, handle, config.GPU_PEAK_FLOPS_METRIC)
-		if err != nvml.SUCCESS {
-			logger.Error("Error collecting peak flops metrics", zap.Error(err))
-		}
+	present := make(map[string]bool, len(uuids))
+	for _, uuid := range uuids {
+		present[uuid] = true
 	}
+	snapshots.Retain(present)
+	notifyDeviceSet(present)
 
-	// @TODO Add more metrics here.
-
-	// @TODO Fix fan speed metrics
-	// err = collectFanSpeedMetrics(handle, metrics, config.GPU_FAN_SPEED)
-	// if err != nvml.SUCCESS {
-	// 	logger.Error("Error collecting fan speed metrics", zap.Error(err))
-	// }
-
-	logger.Debug("Collected GPU metrics", zap.Int("device_index", deviceIndex))
-	return metrics, nil
+	return indexes
 }
 
 // CollectGPUDeviceCount collects the number of GPU devices.
@@ -179,12 +406,12 @@ func CollectGPUDeviceCount(ctx context.Context) (int, error) {
 	var err nvml.Return
 	select {
 	case <-ctx.Done():
-		return 0, fmt.Errorf("context cancelled")
+		return 0, nvmlerrors.New(nvml.ERROR_TIMEOUT, "get device count")
 	default:
 		deviceCount, err = nvml.DeviceGetCount()
 		if err != nvml.SUCCESS {
-			logger.Error("Error getting device count", zap.Error(fmt.Errorf("nvml error code: %d", err)))
-			return 0, err
+			logger.Error("Error getting device count", zap.Error(nvmlerrors.New(err, "get device count")))
+			return 0, nvmlerrors.New(err, "get device count")
 		}
 	}
 
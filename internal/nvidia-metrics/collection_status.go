@@ -0,0 +1,54 @@
+package nvidiametrics
+
+import (
+	"sync"
+	"time"
+)
+
+var collectionStatus = &statusTracker{}
+
+// CollectionStatus describes the state of NVML and the collection loop, it backs the readiness probe.
+type CollectionStatus struct {
+	NvmlInitialized bool
+	DeviceCount     int
+	Interval        time.Duration // tick of the collection loop
+	DefaultInterval time.Duration // collection interval of the metrics without their own interval
+	LastCollection  time.Time
+	LastSuccess     time.Time
+	LastError       string
+}
+
+type statusTracker struct {
+	mu     sync.RWMutex
+	status CollectionStatus
+}
+
+// Status returns the current state of NVML and the collection loop.
+func Status() CollectionStatus {
+	collectionStatus.mu.RLock()
+	defer collectionStatus.mu.RUnlock()
+
+	status := collectionStatus.status
+	status.Interval = collectionScheduler.Tick()
+	status.DefaultInterval = collectionScheduler.DefaultInterval()
+	return status
+}
+
+func (t *statusTracker) setNvmlInitialized(initialized bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.NvmlInitialized = initialized
+}
+
+// collected records the outcome of a collection cycle, an empty error message marks it as succeeded.
+func (t *statusTracker) collected(deviceCount int, errorMessage string, timestamp time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.DeviceCount = deviceCount
+	t.status.LastCollection = timestamp
+	t.status.LastError = errorMessage
+	if errorMessage == "" {
+		t.status.LastSuccess = timestamp
+	}
+}
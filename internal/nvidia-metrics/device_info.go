@@ -0,0 +1,92 @@
+package nvidiametrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// deviceInfo is the last inventory reported for a device.
+type deviceInfo struct {
+	fingerprint string
+	labels      map[string]string
+}
+
+// deviceInfoCache holds the reported inventory per device index, the full inventory is only read again when the fingerprint changes.
+var (
+	deviceInfoCache   = make(map[int]deviceInfo)
+	deviceInfoCacheMu sync.Mutex
+)
+
+// collectDeviceInfoMetrics reports the device inventory as labels on a metric with the value 1.
+// The inventory is read once per device and refreshed when the UUID, persistence mode or compute mode changes.
+func (metrics *GPUDeviceMetrics) collectDeviceInfoMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "device_info", func() nvml.Return {
+		fingerprint, err := deviceInfoFingerprint(handle)
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		deviceInfoCacheMu.Lock()
+		defer deviceInfoCacheMu.Unlock()
+
+		cached, ok := deviceInfoCache[metrics.DeviceIndex]
+		if ok && cached.fingerprint == fingerprint {
+			return nvml.SUCCESS
+		}
+
+		// the inventory changed, drop the old series so only one info series exists per device
+		if ok {
+			prometheusmetrics.DeleteGaugeMetric(metric.GetMetric(), cached.labels)
+		}
+
+		labels := labelManager.GetMetricLabelValues(handle, metric.GetMetric())
+		prometheusmetrics.SetGaugeMetric(metric.GetMetric(), labels, 1)
+		deviceInfoCache[metrics.DeviceIndex] = deviceInfo{fingerprint: fingerprint, labels: labels}
+
+		logger.Info("Updated GPU inventory", zap.Int("device_index", metrics.DeviceIndex), zap.Any("labels", labels))
+
+		return nvml.SUCCESS
+	})
+}
+
+// forgetDeviceInfo drops the inventory and the info series of a device index that was reset,
+// so a removed GPU doesn't keep reporting and a replaced one reads its inventory again.
+func forgetDeviceInfo(deviceIndex int) {
+	deviceInfoCacheMu.Lock()
+	defer deviceInfoCacheMu.Unlock()
+
+	cached, ok := deviceInfoCache[deviceIndex]
+	if !ok {
+		return
+	}
+
+	prometheusmetrics.DeleteGaugeMetric(config.GPU_INFO.GetMetric(), cached.labels)
+	delete(deviceInfoCache, deviceIndex)
+}
+
+// deviceInfoFingerprint reads the inventory fields that can change while the exporter runs.
+func deviceInfoFingerprint(handle nvml.Device) (string, nvml.Return) {
+	uuid, err := handle.GetUUID()
+	if err != nvml.SUCCESS {
+		return "", err
+	}
+
+	persistenceMode, err := handle.GetPersistenceMode()
+	if err != nvml.SUCCESS && err != nvml.ERROR_NOT_SUPPORTED {
+		return "", err
+	}
+
+	computeMode, err := handle.GetComputeMode()
+	if err != nvml.SUCCESS {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%d/%d", uuid, persistenceMode, computeMode), nvml.SUCCESS
+}
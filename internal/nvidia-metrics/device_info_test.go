@@ -0,0 +1,64 @@
+package nvidiametrics
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+)
+
+var _ = Describe("DeviceInfo", func() {
+	var (
+		gpuDeviceMetrics *GPUDeviceMetrics
+		mockHandle       *MockNvmlDevice
+	)
+
+	BeforeEach(func() {
+		gpuDeviceMetrics = NewGPUDeviceMetrics()
+		gpuDeviceMetrics.DeviceIndex = 7
+		mockHandle = new(MockNvmlDevice)
+		mockHandle.On("GetUUID").Return("GPU-1234", nvml.SUCCESS)
+		mockHandle.On("GetPersistenceMode").Return(nvml.FEATURE_ENABLED, nvml.SUCCESS)
+		delete(deviceInfoCache, gpuDeviceMetrics.DeviceIndex)
+	})
+
+	Context("collectDeviceInfoMetrics", func() {
+		It("should refresh the inventory only when the fingerprint changes", func() {
+			mockHandle.On("GetComputeMode").Return(nvml.COMPUTEMODE_DEFAULT, nvml.SUCCESS).Twice()
+
+			Expect(gpuDeviceMetrics.collectDeviceInfoMetrics(ctx, mockHandle, config.GPU_INFO)).To(Succeed())
+			first := deviceInfoCache[gpuDeviceMetrics.DeviceIndex].fingerprint
+
+			Expect(gpuDeviceMetrics.collectDeviceInfoMetrics(ctx, mockHandle, config.GPU_INFO)).To(Succeed())
+			Expect(deviceInfoCache[gpuDeviceMetrics.DeviceIndex].fingerprint).To(Equal(first))
+
+			mockHandle.On("GetComputeMode").Return(nvml.COMPUTEMODE_EXCLUSIVE_PROCESS, nvml.SUCCESS).Once()
+			Expect(gpuDeviceMetrics.collectDeviceInfoMetrics(ctx, mockHandle, config.GPU_INFO)).To(Succeed())
+			Expect(deviceInfoCache[gpuDeviceMetrics.DeviceIndex].fingerprint).NotTo(Equal(first))
+		})
+	})
+
+	Context("forgetDeviceInfo", func() {
+		It("should drop the inventory and the info series of the device", func() {
+			gpuInfo := registerTestGauge(config.GPU_INFO, "gpu_id", "gpu_uuid")
+			labels := map[string]string{"gpu_id": "7", "gpu_uuid": "GPU-1234"}
+			gpuInfo.With(labels).Set(1)
+			deviceInfoCache[gpuDeviceMetrics.DeviceIndex] = deviceInfo{fingerprint: "GPU-1234/1/0", labels: labels}
+
+			forgetDeviceInfo(gpuDeviceMetrics.DeviceIndex)
+
+			Expect(deviceInfoCache).NotTo(HaveKey(gpuDeviceMetrics.DeviceIndex))
+			Expect(testutil.CollectAndCount(gpuInfo)).To(Equal(0))
+		})
+	})
+
+	DescribeTable("int8ToString",
+		func(chars []int8, expected string) {
+			Expect(int8ToString(chars)).To(Equal(expected))
+		},
+		Entry("NUL terminated", []int8{'0', '0', ':', '0', 0, 'x'}, "00:0"),
+		Entry("Not terminated", []int8{'a', 'b'}, "ab"),
+		Entry("Empty", []int8{0}, ""),
+	)
+})
@@ -0,0 +1,100 @@
+package nvidiametrics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/version"
+)
+
+// Metrics about the exporter itself, so a flat graph can be told apart from a broken collector.
+var (
+	collectorRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gpu_metrics_collector_runs_total",
+		Help: "The total number of collector runs by NVML return code.",
+	}, []string{"collector", "return_code"})
+
+	collectorDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gpu_metrics_collector_duration_seconds",
+		Help:    "The duration of the collector runs per device.",
+		Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"collector", "gpu_id"})
+
+	lastSuccessfulCollection = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gpu_metrics_last_successful_collection_timestamp_seconds",
+		Help: "The time of the last collection that succeeded for all GPUs.",
+	})
+
+	collectorSupported = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpu_metrics_collector_supported",
+		Help: "Whether the device supports the collector, 0 once it returned not supported and is skipped.",
+	}, []string{"device", "collector"})
+
+	buildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpu_metrics_build_info",
+		Help: "The build information of the exporter and the NVML library, the value is always 1.",
+	}, []string{"version", "commit", "go_version", "nvml_version", "driver_version"})
+)
+
+// returnCodes names the NVML return codes for the return_code label.
+var returnCodes = map[nvml.Return]string{
+	nvml.SUCCESS:                         "SUCCESS",
+	nvml.ERROR_UNINITIALIZED:             "ERROR_UNINITIALIZED",
+	nvml.ERROR_INVALID_ARGUMENT:          "ERROR_INVALID_ARGUMENT",
+	nvml.ERROR_NOT_SUPPORTED:             "ERROR_NOT_SUPPORTED",
+	nvml.ERROR_NO_PERMISSION:             "ERROR_NO_PERMISSION",
+	nvml.ERROR_NOT_FOUND:                 "ERROR_NOT_FOUND",
+	nvml.ERROR_INSUFFICIENT_SIZE:         "ERROR_INSUFFICIENT_SIZE",
+	nvml.ERROR_DRIVER_NOT_LOADED:         "ERROR_DRIVER_NOT_LOADED",
+	nvml.ERROR_TIMEOUT:                   "ERROR_TIMEOUT",
+	nvml.ERROR_IRQ_ISSUE:                 "ERROR_IRQ_ISSUE",
+	nvml.ERROR_LIBRARY_NOT_FOUND:         "ERROR_LIBRARY_NOT_FOUND",
+	nvml.ERROR_FUNCTION_NOT_FOUND:        "ERROR_FUNCTION_NOT_FOUND",
+	nvml.ERROR_CORRUPTED_INFOROM:         "ERROR_CORRUPTED_INFOROM",
+	nvml.ERROR_GPU_IS_LOST:               "ERROR_GPU_IS_LOST",
+	nvml.ERROR_RESET_REQUIRED:            "ERROR_RESET_REQUIRED",
+	nvml.ERROR_OPERATING_SYSTEM:          "ERROR_OPERATING_SYSTEM",
+	nvml.ERROR_LIB_RM_VERSION_MISMATCH:   "ERROR_LIB_RM_VERSION_MISMATCH",
+	nvml.ERROR_MEMORY:                    "ERROR_MEMORY",
+	nvml.ERROR_NO_DATA:                   "ERROR_NO_DATA",
+	nvml.ERROR_ARGUMENT_VERSION_MISMATCH: "ERROR_ARGUMENT_VERSION_MISMATCH",
+	nvml.ERROR_UNKNOWN:                   "ERROR_UNKNOWN",
+}
+
+// returnCode returns the name of the NVML return code, the number for codes without a name.
+func returnCode(ret nvml.Return) string {
+	if code, ok := returnCodes[ret]; ok {
+		return code
+	}
+	return fmt.Sprintf("ERROR_%d", int32(ret))
+}
+
+// observeCollector runs the collector for the device, counting the run by the NVML return code of its error and timing it.
+// Collectors the device doesn't support are skipped without running.
+func observeCollector(deviceIndex int, uuid string, collector string, collect func() error) error {
+	if !collectorEnabled(uuid, collector) {
+		return nil
+	}
+
+	start := time.Now()
+	err := collect()
+
+	collectorDuration.WithLabelValues(collector, strconv.Itoa(deviceIndex)).Observe(time.Since(start).Seconds())
+	collectorRuns.WithLabelValues(collector, returnCode(nvmlerrors.ReturnCode(err))).Inc()
+
+	recordCapability(deviceIndex, uuid, collector, err)
+	return err
+}
+
+// recordBuildInfo sets the build info, the NVML and driver versions are only known once NVML is initialized.
+func recordBuildInfo() {
+	nvmlVersion, driverVersion := systemVersions()
+
+	buildInfo.Reset()
+	buildInfo.WithLabelValues(version.Version, version.GetCommit(), version.GoVersion(), nvmlVersion, driverVersion).Set(1)
+}
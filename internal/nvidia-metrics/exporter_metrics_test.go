@@ -0,0 +1,36 @@
+package nvidiametrics
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
+)
+
+var _ = Describe("Exporter metrics", func() {
+	DescribeTable("returnCode",
+		func(ret nvml.Return, expected string) {
+			Expect(returnCode(ret)).To(Equal(expected))
+		},
+		Entry("success", nvml.SUCCESS, "SUCCESS"),
+		Entry("not supported", nvml.ERROR_NOT_SUPPORTED, "ERROR_NOT_SUPPORTED"),
+		Entry("gpu lost", nvml.ERROR_GPU_IS_LOST, "ERROR_GPU_IS_LOST"),
+		Entry("unnamed code", nvml.Return(12345), "ERROR_12345"),
+	)
+
+	It("should count collector runs by return code", func() {
+		collectorCapabilities = NewCapabilities()
+		DeferCleanup(func() { collectorCapabilities = NewCapabilities() })
+
+		before := testutil.ToFloat64(collectorRuns.WithLabelValues("fan_speed", "ERROR_NOT_SUPPORTED"))
+
+		err := observeCollector(0, "GPU-0", "fan_speed", func() error {
+			return nvmlerrors.New(nvml.ERROR_NOT_SUPPORTED, "fan_speed")
+		})
+
+		Expect(nvmlerrors.IsNotSupported(err)).To(BeTrue())
+		Expect(testutil.ToFloat64(collectorRuns.WithLabelValues("fan_speed", "ERROR_NOT_SUPPORTED"))).To(Equal(before + 1))
+		Expect(testutil.CollectAndCount(collectorDuration)).To(BeNumerically(">=", 1))
+	})
+})
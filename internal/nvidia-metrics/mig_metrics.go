@@ -0,0 +1,221 @@
+package nvidiametrics
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// MigDeviceMetrics represents the collected metrics for a MIG device, one per compute instance.
+type MigDeviceMetrics struct {
+	GpuInstanceId     int
+	ComputeInstanceId int
+	Profile           string
+	MemoryUsed        uint64
+	MemoryTotal       uint64
+	RunningProcesses  int
+}
+
+// migSeries tracks the per MIG device series, so the series of removed instances are deleted after repartitioning.
+var migSeries = NewSeriesTracker()
+
+// collectMigMetrics collects the MIG mode of the GPU device and, when enabled, the number of GPU and compute
+// instances and the memory usage and running processes of every MIG device.
+// MIG series are reported against the parent GPU labels.
+func (metrics *GPUDeviceMetrics) collectMigMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "mig", func() nvml.Return {
+		current, _, err := handle.GetMigMode()
+		if err == nvml.ERROR_NOT_SUPPORTED {
+			// GPUs without MIG support report MIG as disabled
+			current, err = nvml.DEVICE_MIG_DISABLE, nvml.SUCCESS
+		}
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		metrics.MigEnabled = current == nvml.DEVICE_MIG_ENABLE
+		if isRegistered(config.GPU_MIG_MODE) {
+			SetDeviceMetric(handle, config.GPU_MIG_MODE, boolToFloat(metrics.MigEnabled))
+		}
+
+		migSeries.Begin(metrics.DeviceIndex)
+		metrics.MigDevices = nil
+
+		if !metrics.MigEnabled {
+			// MIG was disabled, drop the series of the former MIG devices
+			migSeries.Sweep(metrics.DeviceIndex)
+			return nvml.SUCCESS
+		}
+
+		maxMigDevices, err := handle.GetMaxMigDeviceCount()
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		handleGpuInstances := make(map[int]bool)
+		for i := 0; i < maxMigDevices; i++ {
+			migHandle, err := handle.GetMigDeviceHandleByIndex(i)
+			if err == nvml.ERROR_NOT_FOUND {
+				// unpopulated MIG device slot
+				continue
+			}
+			if err != nvml.SUCCESS {
+				return err
+			}
+
+			migDevice, err := collectMigDevice(migHandle)
+			if err != nvml.SUCCESS {
+				return err
+			}
+
+			metrics.MigDevices = append(metrics.MigDevices, migDevice)
+			handleGpuInstances[migDevice.GpuInstanceId] = true
+
+			migLabels := map[string]string{
+				config.GPU_MIG_PROFILE.GetLabel():         migDevice.Profile,
+				config.GPU_INSTANCE_ID.GetLabel():         strconv.Itoa(migDevice.GpuInstanceId),
+				config.GPU_COMPUTE_INSTANCE_ID.GetLabel(): strconv.Itoa(migDevice.ComputeInstanceId),
+			}
+
+			if isRegistered(config.GPU_MIG_MEMORY_USED) {
+				migSeries.Set(metrics.DeviceIndex, handle, config.GPU_MIG_MEMORY_USED, migLabels, float64(migDevice.MemoryUsed))
+			}
+
+			if isRegistered(config.GPU_MIG_MEMORY_TOTAL) {
+				migSeries.Set(metrics.DeviceIndex, handle, config.GPU_MIG_MEMORY_TOTAL, migLabels, float64(migDevice.MemoryTotal))
+			}
+
+			if isRegistered(config.GPU_MIG_RUNNING_PROCESS) {
+				migSeries.Set(metrics.DeviceIndex, handle, config.GPU_MIG_RUNNING_PROCESS, migLabels, float64(migDevice.RunningProcesses))
+			}
+		}
+
+		migSeries.Sweep(metrics.DeviceIndex)
+
+		gpuInstances, computeInstances, err := migInstanceCounts(handle)
+		if err == nvml.ERROR_NO_PERMISSION {
+			// listing the instances needs a privileged user, count the populated MIG devices instead
+			logger.Debug("No permission to list MIG instances, counting MIG devices", zap.Int("device_index", metrics.DeviceIndex))
+			gpuInstances, computeInstances, err = len(handleGpuInstances), len(metrics.MigDevices), nvml.SUCCESS
+		}
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		if isRegistered(config.GPU_MIG_GPU_INSTANCES) {
+			SetDeviceMetric(handle, config.GPU_MIG_GPU_INSTANCES, float64(gpuInstances))
+		}
+
+		if isRegistered(config.GPU_MIG_COMPUTE_INSTANCES) {
+			SetDeviceMetric(handle, config.GPU_MIG_COMPUTE_INSTANCES, float64(computeInstances))
+		}
+
+		return nvml.SUCCESS
+	})
+}
+
+// migInstanceCounts counts the GPU instances of every GPU instance profile and their compute instances,
+// including instances that have no MIG device handle, e.g. a GPU instance without compute instances.
+func migInstanceCounts(handle nvml.Device) (int, int, nvml.Return) {
+	gpuInstances, computeInstances := 0, 0
+	for profile := 0; profile < nvml.GPU_INSTANCE_PROFILE_COUNT; profile++ {
+		profileInfo, err := handle.GetGpuInstanceProfileInfo(profile)
+		if err == nvml.ERROR_NOT_SUPPORTED || err == nvml.ERROR_INVALID_ARGUMENT {
+			// the profile doesn't exist on this GPU
+			continue
+		}
+		if err != nvml.SUCCESS {
+			return 0, 0, err
+		}
+
+		instances, err := handle.GetGpuInstances(&profileInfo)
+		if err != nvml.SUCCESS {
+			return 0, 0, err
+		}
+
+		gpuInstances += len(instances)
+		for _, instance := range instances {
+			count, err := computeInstanceCount(instance)
+			if err != nvml.SUCCESS {
+				return 0, 0, err
+			}
+			computeInstances += count
+		}
+	}
+
+	return gpuInstances, computeInstances, nvml.SUCCESS
+}
+
+// computeInstanceCount counts the compute instances of every compute instance profile of the GPU instance.
+func computeInstanceCount(instance nvml.GpuInstance) (int, nvml.Return) {
+	count := 0
+	for profile := 0; profile < nvml.COMPUTE_INSTANCE_PROFILE_COUNT; profile++ {
+		profileInfo, err := instance.GetComputeInstanceProfileInfo(profile, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED)
+		if err == nvml.ERROR_NOT_SUPPORTED || err == nvml.ERROR_INVALID_ARGUMENT {
+			// the profile doesn't exist on this GPU instance
+			continue
+		}
+		if err != nvml.SUCCESS {
+			return 0, err
+		}
+
+		computeInstances, err := instance.GetComputeInstances(&profileInfo)
+		if err != nvml.SUCCESS {
+			return 0, err
+		}
+		count += len(computeInstances)
+	}
+
+	return count, nvml.SUCCESS
+}
+
+// collectMigDevice reads the instance ids, profile, memory and processes of a MIG device handle.
+func collectMigDevice(migHandle nvml.Device) (MigDeviceMetrics, nvml.Return) {
+	var migDevice MigDeviceMetrics
+	var err nvml.Return
+
+	migDevice.GpuInstanceId, err = migHandle.GetGpuInstanceId()
+	if err != nvml.SUCCESS {
+		return migDevice, err
+	}
+
+	migDevice.ComputeInstanceId, err = migHandle.GetComputeInstanceId()
+	if err != nvml.SUCCESS {
+		return migDevice, err
+	}
+
+	name, err := migHandle.GetName()
+	if err != nvml.SUCCESS {
+		return migDevice, err
+	}
+	migDevice.Profile = migProfileFromName(name)
+
+	memoryInfo, err := migHandle.GetMemoryInfo()
+	if err != nvml.SUCCESS {
+		return migDevice, err
+	}
+	migDevice.MemoryUsed = memoryInfo.Used
+	migDevice.MemoryTotal = memoryInfo.Total
+
+	processes, err := migHandle.GetComputeRunningProcesses()
+	if err != nvml.SUCCESS {
+		return migDevice, err
+	}
+	migDevice.RunningProcesses = len(processes)
+
+	return migDevice, nvml.SUCCESS
+}
+
+// migProfileFromName returns the profile of a MIG device from its name.
+// Example: "NVIDIA A100-SXM4-40GB MIG 1g.5gb" returns "1g.5gb"
+func migProfileFromName(name string) string {
+	if _, profile, ok := strings.Cut(name, " MIG "); ok {
+		return strings.TrimSpace(profile)
+	}
+	return "unknown"
+}
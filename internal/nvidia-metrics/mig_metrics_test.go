@@ -0,0 +1,213 @@
+package nvidiametrics
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockGpuInstance is a mock implementation of the nvml.GpuInstance interface.
+type MockGpuInstance struct {
+	mock.Mock
+	nvml.GpuInstance
+}
+
+func (m *MockGpuInstance) GetComputeInstanceProfileInfo(profile int, engProfile int) (nvml.ComputeInstanceProfileInfo, nvml.Return) {
+	args := m.Called(profile, engProfile)
+	return args.Get(0).(nvml.ComputeInstanceProfileInfo), args.Get(1).(nvml.Return)
+}
+
+func (m *MockGpuInstance) GetComputeInstances(info *nvml.ComputeInstanceProfileInfo) ([]nvml.ComputeInstance, nvml.Return) {
+	args := m.Called(info)
+	return args.Get(0).([]nvml.ComputeInstance), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetMigMode() (int, int, nvml.Return) {
+	args := m.Called()
+	return args.Int(0), args.Int(1), args.Get(2).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetMaxMigDeviceCount() (int, nvml.Return) {
+	args := m.Called()
+	return args.Int(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetMigDeviceHandleByIndex(index int) (nvml.Device, nvml.Return) {
+	args := m.Called(index)
+	return args.Get(0).(nvml.Device), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetGpuInstanceProfileInfo(profile int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+	args := m.Called(profile)
+	return args.Get(0).(nvml.GpuInstanceProfileInfo), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetGpuInstances(info *nvml.GpuInstanceProfileInfo) ([]nvml.GpuInstance, nvml.Return) {
+	args := m.Called(info)
+	return args.Get(0).([]nvml.GpuInstance), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetGpuInstanceId() (int, nvml.Return) {
+	args := m.Called()
+	return args.Int(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetComputeInstanceId() (int, nvml.Return) {
+	args := m.Called()
+	return args.Int(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetName() (string, nvml.Return) {
+	args := m.Called()
+	return args.String(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetMemoryInfo() (nvml.Memory, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(nvml.Memory), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetComputeRunningProcesses() ([]nvml.ProcessInfo, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).([]nvml.ProcessInfo), args.Get(1).(nvml.Return)
+}
+
+// newMockMigDevice creates a MIG device handle of the GPU and compute instance.
+func newMockMigDevice(gpuInstanceId int, computeInstanceId int, profile string, memoryUsed uint64) *MockNvmlDevice {
+	migHandle := new(MockNvmlDevice)
+	migHandle.On("GetGpuInstanceId").Return(gpuInstanceId, nvml.SUCCESS)
+	migHandle.On("GetComputeInstanceId").Return(computeInstanceId, nvml.SUCCESS)
+	migHandle.On("GetName").Return("NVIDIA A100-SXM4-40GB MIG "+profile, nvml.SUCCESS)
+	migHandle.On("GetMemoryInfo").Return(nvml.Memory{Used: memoryUsed, Total: 5 << 30}, nvml.SUCCESS)
+	migHandle.On("GetComputeRunningProcesses").Return([]nvml.ProcessInfo{}, nvml.SUCCESS)
+	return migHandle
+}
+
+// newMockGpuInstance creates a GPU instance with the number of compute instances of the first compute profile.
+func newMockGpuInstance(computeInstances int) *MockGpuInstance {
+	gpuInstance := new(MockGpuInstance)
+	gpuInstance.On("GetComputeInstanceProfileInfo", 0, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED).Return(nvml.ComputeInstanceProfileInfo{Id: 0}, nvml.SUCCESS)
+	gpuInstance.On("GetComputeInstanceProfileInfo", mock.Anything, mock.Anything).Return(nvml.ComputeInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED)
+	gpuInstance.On("GetComputeInstances", mock.Anything).Return(make([]nvml.ComputeInstance, computeInstances), nvml.SUCCESS)
+	return gpuInstance
+}
+
+var _ = Describe("MIG", func() {
+	DescribeTable("migProfileFromName",
+		func(name string, expected string) {
+			Expect(migProfileFromName(name)).To(Equal(expected))
+		},
+		Entry("A100 MIG device", "NVIDIA A100-SXM4-40GB MIG 1g.5gb", "1g.5gb"),
+		Entry("H100 MIG device with media extensions", "NVIDIA H100 80GB HBM3 MIG 1g.10gb+me", "1g.10gb+me"),
+		Entry("Not a MIG device", "NVIDIA GeForce RTX 3060", "unknown"),
+	)
+
+	Context("collectMigMetrics", func() {
+		var (
+			gpuDeviceMetrics *GPUDeviceMetrics
+			mockHandle       *MockNvmlDevice
+		)
+
+		migLabelNames := []string{
+			config.GPU_MIG_PROFILE.GetLabel(),
+			config.GPU_INSTANCE_ID.GetLabel(),
+			config.GPU_COMPUTE_INSTANCE_ID.GetLabel(),
+		}
+
+		BeforeEach(func() {
+			gpuDeviceMetrics = &GPUDeviceMetrics{DeviceIndex: 3}
+			mockHandle = new(MockNvmlDevice)
+
+			migSeries = NewSeriesTracker()
+			DeferCleanup(func() { migSeries = NewSeriesTracker() })
+		})
+
+		It("should count GPU instances without MIG devices", func() {
+			gpuInstances := registerTestGauge(config.GPU_MIG_GPU_INSTANCES)
+			computeInstances := registerTestGauge(config.GPU_MIG_COMPUTE_INSTANCES)
+
+			mockHandle.On("GetMigMode").Return(nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS)
+			mockHandle.On("GetMaxMigDeviceCount").Return(2, nvml.SUCCESS)
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(newMockMigDevice(1, 0, "1g.5gb", 1<<30), nvml.SUCCESS)
+			mockHandle.On("GetMigDeviceHandleByIndex", 1).Return(new(MockNvmlDevice), nvml.ERROR_NOT_FOUND)
+			mockHandle.On("GetGpuInstanceProfileInfo", 0).Return(nvml.GpuInstanceProfileInfo{Id: 0}, nvml.SUCCESS)
+			mockHandle.On("GetGpuInstanceProfileInfo", mock.Anything).Return(nvml.GpuInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED)
+			// the second GPU instance has no compute instance, so no MIG device handle
+			mockHandle.On("GetGpuInstances", mock.Anything).Return([]nvml.GpuInstance{newMockGpuInstance(1), newMockGpuInstance(0)}, nvml.SUCCESS)
+
+			err := gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.MigDevices).To(HaveLen(1))
+			Expect(testutil.ToFloat64(gpuInstances)).To(Equal(2.0))
+			Expect(testutil.ToFloat64(computeInstances)).To(Equal(1.0))
+		})
+
+		It("should count the MIG devices without permission to list the instances", func() {
+			gpuInstances := registerTestGauge(config.GPU_MIG_GPU_INSTANCES)
+
+			mockHandle.On("GetMigMode").Return(nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS)
+			mockHandle.On("GetMaxMigDeviceCount").Return(1, nvml.SUCCESS)
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(newMockMigDevice(1, 0, "1g.5gb", 1<<30), nvml.SUCCESS)
+			mockHandle.On("GetGpuInstanceProfileInfo", 0).Return(nvml.GpuInstanceProfileInfo{Id: 0}, nvml.SUCCESS)
+			mockHandle.On("GetGpuInstances", mock.Anything).Return([]nvml.GpuInstance{}, nvml.ERROR_NO_PERMISSION)
+
+			err := gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(testutil.ToFloat64(gpuInstances)).To(Equal(1.0))
+		})
+
+		It("should delete the series of MIG devices removed by repartitioning", func() {
+			memoryUsed := registerTestGauge(config.GPU_MIG_MEMORY_USED, migLabelNames...)
+
+			mockHandle.On("GetMigMode").Return(nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS)
+			mockHandle.On("GetMaxMigDeviceCount").Return(1, nvml.SUCCESS)
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(newMockMigDevice(1, 0, "1g.5gb", 1<<30), nvml.SUCCESS).Once()
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(newMockMigDevice(2, 0, "2g.10gb", 2<<30), nvml.SUCCESS).Once()
+			mockHandle.On("GetGpuInstanceProfileInfo", mock.Anything).Return(nvml.GpuInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED)
+
+			Expect(gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)).To(Succeed())
+			Expect(gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)).To(Succeed())
+
+			Expect(testutil.CollectAndCount(memoryUsed)).To(Equal(1))
+			Expect(testutil.ToFloat64(memoryUsed.WithLabelValues("2g.10gb", "2", "0"))).To(Equal(float64(2 << 30)))
+			Expect(migSeries.Len(3)).To(Equal(1))
+		})
+
+		It("should delete the MIG device series when MIG is disabled", func() {
+			memoryUsed := registerTestGauge(config.GPU_MIG_MEMORY_USED, migLabelNames...)
+
+			mockHandle.On("GetMigMode").Return(nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS).Once()
+			mockHandle.On("GetMigMode").Return(nvml.DEVICE_MIG_DISABLE, nvml.DEVICE_MIG_DISABLE, nvml.SUCCESS).Once()
+			mockHandle.On("GetMaxMigDeviceCount").Return(1, nvml.SUCCESS)
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(newMockMigDevice(1, 0, "1g.5gb", 1<<30), nvml.SUCCESS)
+			mockHandle.On("GetGpuInstanceProfileInfo", mock.Anything).Return(nvml.GpuInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED)
+
+			Expect(gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)).To(Succeed())
+			Expect(testutil.CollectAndCount(memoryUsed)).To(Equal(1))
+
+			Expect(gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)).To(Succeed())
+			Expect(testutil.CollectAndCount(memoryUsed)).To(Equal(0))
+			Expect(gpuDeviceMetrics.MigDevices).To(BeEmpty())
+		})
+
+		It("should keep the series when a collection fails halfway", func() {
+			memoryUsed := registerTestGauge(config.GPU_MIG_MEMORY_USED, migLabelNames...)
+
+			mockHandle.On("GetMigMode").Return(nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS)
+			mockHandle.On("GetMaxMigDeviceCount").Return(1, nvml.SUCCESS)
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(newMockMigDevice(1, 0, "1g.5gb", 1<<30), nvml.SUCCESS).Once()
+			mockHandle.On("GetMigDeviceHandleByIndex", 0).Return(new(MockNvmlDevice), nvml.ERROR_UNKNOWN).Once()
+			mockHandle.On("GetGpuInstanceProfileInfo", mock.Anything).Return(nvml.GpuInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED)
+
+			Expect(gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)).To(Succeed())
+			Expect(gpuDeviceMetrics.collectMigMetrics(ctx, mockHandle)).To(MatchError(nvml.ERROR_UNKNOWN))
+
+			Expect(testutil.CollectAndCount(memoryUsed)).To(Equal(1))
+		})
+	})
+})
@@ -1,6 +1,9 @@
 package nvidiametrics
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/rupeshtr78/nvidia-metrics/internal/config"
 	gauge "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
@@ -12,6 +15,50 @@ func SetDeviceMetric(handle nvml.Device, metricConfig config.Metric, metricValue
 	metric := metricConfig.GetMetric()
 	metricLabels := labelManager.GetMetricLabelValues(handle, metric)
 	gauge.SetGaugeMetric(metric, metricLabels, metricValue)
+	recordSnapshot(handle, metric, nil, metricValue)
+}
+
+// SetDeviceMetricWithLabels sets the metric value for the given device with additional per series labels
+func SetDeviceMetricWithLabels(handle nvml.Device, metricConfig config.Metric, extraLabels map[string]string, metricValue float64) {
+	metric := metricConfig.GetMetric()
+	metricLabels := labelManager.GetMetricLabelValuesWith(handle, metric, extraLabels)
+	gauge.SetGaugeMetric(metric, metricLabels, metricValue)
+	recordSnapshot(handle, metric, extraLabels, metricValue)
+}
+
+// SetDeviceCounterWithLabels sets the counter for the given device to the running total reported by NVML
+func SetDeviceCounterWithLabels(handle nvml.Device, metricConfig config.Metric, extraLabels map[string]string, total float64) {
+	metric := metricConfig.GetMetric()
+	metricLabels := labelManager.GetMetricLabelValuesWith(handle, metric, extraLabels)
+	gauge.SetCounterMetric(metric, metricLabels, total)
+	recordSnapshot(handle, metric, extraLabels, total)
+}
+
+// staticLabels are the labels whose value doesn't change for a device while the device set is unchanged,
+// their values are fetched once and cached per device UUID. All other labels are fetched on every set.
+var staticLabels = map[string]bool{
+	config.GPU_ID.GetLabel():                      true,
+	config.GPU_NAME.GetLabel():                    true,
+	config.GPU_TEM_THRESHOLD.GetLabel():           true,
+	config.GPU_MEM_CLOCK_MAX.GetLabel():           true,
+	config.GPU_SM_CLOCK_MAX.GetLabel():            true,
+	config.GPU_GRAPHICS_CLOCK_MAX.GetLabel():      true,
+	config.GPU_CORES.GetLabel():                   true,
+	config.GPU_DRIVER_VERSION.GetLabel():          true,
+	config.GPU_CUDA_VERSION.GetLabel():            true,
+	config.GPU_PEAK_FLOPS.GetLabel():              true,
+	config.GPU_UUID.GetLabel():                    true,
+	config.GPU_SERIAL.GetLabel():                  true,
+	config.GPU_BOARD_PART_NUMBER.GetLabel():       true,
+	config.GPU_VBIOS_VERSION.GetLabel():           true,
+	config.GPU_INFOROM_IMAGE_VERSION.GetLabel():   true,
+	config.GPU_INFOROM_OEM_VERSION.GetLabel():     true,
+	config.GPU_INFOROM_ECC_VERSION.GetLabel():     true,
+	config.GPU_PCI_BUS_ID.GetLabel():              true,
+	config.GPU_PCI_DEVICE_ID.GetLabel():           true,
+	config.GPU_PCI_SUBSYSTEM_ID.GetLabel():        true,
+	config.GPU_ARCHITECTURE.GetLabel():            true,
+	config.GPU_CUDA_COMPUTE_CAPABILITY.GetLabel(): true,
 }
 
 // AddFunctions adds the label function to the map
@@ -82,6 +129,76 @@ func (lf LabelFunctions) AddFunctions() {
 		return tflops, nvml.SUCCESS
 	})
 
+	lf.Add(config.GPU_UUID.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return device.GetUUID()
+	})
+
+	lf.Add(config.GPU_SERIAL.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return inventoryLabel(device.GetSerial())
+	})
+
+	lf.Add(config.GPU_BOARD_PART_NUMBER.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return inventoryLabel(device.GetBoardPartNumber())
+	})
+
+	lf.Add(config.GPU_VBIOS_VERSION.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return inventoryLabel(device.GetVbiosVersion())
+	})
+
+	lf.Add(config.GPU_INFOROM_IMAGE_VERSION.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return inventoryLabel(device.GetInforomImageVersion())
+	})
+
+	lf.Add(config.GPU_INFOROM_OEM_VERSION.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return inventoryLabel(device.GetInforomVersion(nvml.INFOROM_OEM))
+	})
+
+	lf.Add(config.GPU_INFOROM_ECC_VERSION.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		return inventoryLabel(device.GetInforomVersion(nvml.INFOROM_ECC))
+	})
+
+	lf.Add(config.GPU_PCI_BUS_ID.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		pciInfo, ret := device.GetPciInfo()
+		return int8ToString(pciInfo.BusId[:]), ret
+	})
+
+	lf.Add(config.GPU_PCI_DEVICE_ID.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		pciInfo, ret := device.GetPciInfo()
+		return fmt.Sprintf("0x%08x", pciInfo.PciDeviceId), ret
+	})
+
+	lf.Add(config.GPU_PCI_SUBSYSTEM_ID.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		pciInfo, ret := device.GetPciInfo()
+		return fmt.Sprintf("0x%08x", pciInfo.PciSubSystemId), ret
+	})
+
+	lf.Add(config.GPU_ARCHITECTURE.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		arch, ret := device.GetArchitecture()
+		if name, ok := architectureNames[arch]; ok {
+			return name, ret
+		}
+		return "unknown", ret
+	})
+
+	lf.Add(config.GPU_CUDA_COMPUTE_CAPABILITY.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		major, minor, ret := device.GetCudaComputeCapability()
+		return fmt.Sprintf("%d.%d", major, minor), ret
+	})
+
+	lf.Add(config.GPU_PERSISTENCE_MODE.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		mode, ret := device.GetPersistenceMode()
+		if ret == nvml.ERROR_NOT_SUPPORTED {
+			// persistence mode is linux only
+			return "", nvml.SUCCESS
+		}
+		return enableStateNames[mode], ret
+	})
+
+	lf.Add(config.GPU_COMPUTE_MODE.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
+		mode, ret := device.GetComputeMode()
+		return computeModeNames[mode], ret
+	})
+
 	// @TODO add additional label function to the map
 	//lf.Add(config.GPU_POWER.GetLabel(), func(device nvml.Device) (any, nvml.Return) {
 	//	operationMode, _, r := device.GetGpuOperationMode()
@@ -90,3 +207,49 @@ func (lf LabelFunctions) AddFunctions() {
 	logger.Debug("Collected GPU Labels")
 
 }
+
+// architectureNames maps the NVML device architecture to the gpu_architecture label value.
+var architectureNames = map[nvml.DeviceArchitecture]string{
+	nvml.DEVICE_ARCH_KEPLER:  "kepler",
+	nvml.DEVICE_ARCH_MAXWELL: "maxwell",
+	nvml.DEVICE_ARCH_PASCAL:  "pascal",
+	nvml.DEVICE_ARCH_VOLTA:   "volta",
+	nvml.DEVICE_ARCH_TURING:  "turing",
+	nvml.DEVICE_ARCH_AMPERE:  "ampere",
+	nvml.DEVICE_ARCH_ADA:     "ada",
+	nvml.DEVICE_ARCH_HOPPER:  "hopper",
+}
+
+// computeModeNames maps the NVML compute mode to the gpu_compute_mode label value.
+var computeModeNames = map[nvml.ComputeMode]string{
+	nvml.COMPUTEMODE_DEFAULT:           "default",
+	nvml.COMPUTEMODE_EXCLUSIVE_THREAD:  "exclusive_thread",
+	nvml.COMPUTEMODE_PROHIBITED:        "prohibited",
+	nvml.COMPUTEMODE_EXCLUSIVE_PROCESS: "exclusive_process",
+}
+
+// enableStateNames maps the NVML enable state to a label value.
+var enableStateNames = map[nvml.EnableState]string{
+	nvml.FEATURE_DISABLED: "disabled",
+	nvml.FEATURE_ENABLED:  "enabled",
+}
+
+// inventoryLabel returns an empty label value for inventory fields the GPU doesn't report, e.g. serial numbers on GeForce cards.
+func inventoryLabel(value string, ret nvml.Return) (any, nvml.Return) {
+	if ret == nvml.ERROR_NOT_SUPPORTED {
+		return "", nvml.SUCCESS
+	}
+	return value, ret
+}
+
+// int8ToString converts a NUL terminated C char array to a string.
+func int8ToString(chars []int8) string {
+	var b strings.Builder
+	for _, c := range chars {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(byte(c))
+	}
+	return b.String()
+}
@@ -2,16 +2,23 @@ package nvidiametrics
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"math"
+	"strconv"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/rupeshtr78/nvidia-metrics/internal/config"
 	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
 	"go.uber.org/zap"
 )
 
 func isRegistered(metric config.Metric) bool {
+	if _, ok := prometheusmetrics.RegisteredCounters[metric.GetMetric()]; ok {
+		return true
+	}
 	if _, ok := prometheusmetrics.RegisteredMetrics[metric.GetMetric()]; !ok {
 		logger.Debug("metric not registered", zap.String("metric", metric.GetMetric()))
 		return false
@@ -19,22 +26,23 @@ func isRegistered(metric config.Metric) bool {
 	return true
 }
 
-// WithContext is a helper function to execute a function with context.
-func WithContext(ctx context.Context, fn func() nvml.Return) nvml.Return {
+// WithContext runs the NVML calls of the collector op unless the context is done.
+// The return code is wrapped in an nvmlerrors error for the op, nil on success.
+func WithContext(ctx context.Context, op string, fn func() nvml.Return) error {
 	select {
 	case <-ctx.Done():
 		fmt.Println("Context canceled before executing function", ctx.Err())
-		return nvml.ERROR_TIMEOUT
+		return nvmlerrors.New(nvml.ERROR_TIMEOUT, op)
 
 	default:
-		return fn()
+		return nvmlerrors.New(fn(), op)
 	}
 }
 
 // CollectDeviceMetrics collects all the metrics for the GPU device.
-func (metrics *GPUDeviceMetrics) CollectUtilizationMetrics(ctx context.Context, handle nvml.Device) nvml.Return {
+func (metrics *GPUDeviceMetrics) CollectUtilizationMetrics(ctx context.Context, handle nvml.Device) error {
 
-	return WithContext(ctx, func() nvml.Return {
+	return WithContext(ctx, "utilization", func() nvml.Return {
 		utilization, err := handle.GetUtilizationRates()
 		if err == nvml.SUCCESS {
 			metrics.GPUCPUUtilization = float64(utilization.Gpu)
@@ -48,21 +56,53 @@ func (metrics *GPUDeviceMetrics) CollectUtilizationMetrics(ctx context.Context,
 	})
 }
 
-// CollectMemoryInfoMetrics collects the memory usage metrics for the GPU device.
-func (metrics *GPUDeviceMetrics) CollectMemoryInfoMetrics(ctx context.Context, handle nvml.Device) nvml.Return {
+// CollectMemoryInfoMetrics collects the memory usage metrics in bytes for the GPU device.
+// Reserved memory is only reported by drivers supporting the v2 memory info API.
+func (metrics *GPUDeviceMetrics) CollectMemoryInfoMetrics(ctx context.Context, handle nvml.Device) error {
 
-	return WithContext(ctx, func() nvml.Return {
-		memoryInfo, err := handle.GetMemoryInfo()
+	return WithContext(ctx, "memory_info", func() nvml.Return {
+		memoryInfo, err := handle.GetMemoryInfo_v2()
+		if err == nvml.ERROR_NOT_SUPPORTED || err == nvml.ERROR_FUNCTION_NOT_FOUND || err == nvml.ERROR_ARGUMENT_VERSION_MISMATCH {
+			memory, v1Err := handle.GetMemoryInfo()
+			memoryInfo, err = nvml.Memory_v2{Total: memory.Total, Free: memory.Free, Used: memory.Used}, v1Err
+		}
 
 		if err == nvml.SUCCESS {
-			// Memory usage is in bytes, converting to GB.
-			metrics.GPUMemoryUsed = uint64(memoryInfo.Used) / 1024 / 1024 //  memory is in bytes and we want MB
-			metrics.GPUMemoryTotal = uint64(memoryInfo.Total) / 1024 / 1024 / 1024
-			metrics.GPUMemoryFree = uint64(memoryInfo.Free) / 1024 / 1024 / 1024
+			metrics.GPUMemoryUsed = memoryInfo.Used
+			metrics.GPUMemoryTotal = memoryInfo.Total
+			metrics.GPUMemoryFree = memoryInfo.Free
+			metrics.GPUMemoryReserved = memoryInfo.Reserved
 
 			SetDeviceMetric(handle, config.GPU_MEMORY_USED, float64(metrics.GPUMemoryUsed))
 			SetDeviceMetric(handle, config.GPU_MEMORY_TOTAL, float64(metrics.GPUMemoryTotal))
 			SetDeviceMetric(handle, config.GPU_MEMORY_FREE, float64(metrics.GPUMemoryFree))
+			if isRegistered(config.GPU_MEMORY_RESERVED) {
+				SetDeviceMetric(handle, config.GPU_MEMORY_RESERVED, float64(metrics.GPUMemoryReserved))
+			}
+		}
+
+		return err
+	})
+}
+
+// collectBar1MemoryMetrics collects the BAR1 memory usage in bytes, the memory mapped for direct access by the CPU and peer devices.
+func (metrics *GPUDeviceMetrics) collectBar1MemoryMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "bar1_memory", func() nvml.Return {
+		bar1Memory, err := handle.GetBAR1MemoryInfo()
+		if err == nvml.SUCCESS {
+			metrics.GPUBar1MemoryUsed = bar1Memory.Bar1Used
+			metrics.GPUBar1MemoryTotal = bar1Memory.Bar1Total
+			metrics.GPUBar1MemoryFree = bar1Memory.Bar1Free
+
+			if isRegistered(config.GPU_BAR1_MEMORY_USED) {
+				SetDeviceMetric(handle, config.GPU_BAR1_MEMORY_USED, float64(metrics.GPUBar1MemoryUsed))
+			}
+			if isRegistered(config.GPU_BAR1_MEMORY_TOTAL) {
+				SetDeviceMetric(handle, config.GPU_BAR1_MEMORY_TOTAL, float64(metrics.GPUBar1MemoryTotal))
+			}
+			if isRegistered(config.GPU_BAR1_MEMORY_FREE) {
+				SetDeviceMetric(handle, config.GPU_BAR1_MEMORY_FREE, float64(metrics.GPUBar1MemoryFree))
+			}
 		}
 
 		return err
@@ -70,8 +110,8 @@ func (metrics *GPUDeviceMetrics) CollectMemoryInfoMetrics(ctx context.Context, h
 }
 
 // CollectPowerInfoMetrics collects the power usage metrics for the GPU device.
-func (metrics *GPUDeviceMetrics) CollectPowerInfoMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) CollectPowerInfoMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "power_info", func() nvml.Return {
 		gpuPowerUsage, err := handle.GetPowerUsage()
 		if err == nvml.SUCCESS {
 			metrics.GPUPowerUsage = float64(gpuPowerUsage) / 1000 // Assuming power is in mW and we want W.
@@ -83,8 +123,8 @@ func (metrics *GPUDeviceMetrics) CollectPowerInfoMetrics(ctx context.Context, ha
 }
 
 // CollectRunningProcessMetrics collects the number of running processes on the GPU device.
-func (metrics *GPUDeviceMetrics) CollectRunningProcessMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) CollectRunningProcessMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "running_process", func() nvml.Return {
 		runningProcess, err := handle.GetComputeRunningProcesses()
 		if err == nvml.SUCCESS {
 			metrics.GPURunningProcesses = len(runningProcess)
@@ -96,8 +136,8 @@ func (metrics *GPUDeviceMetrics) CollectRunningProcessMetrics(ctx context.Contex
 }
 
 // CollectTemperatureMetrics collects the temperature metrics for the GPU device.
-func (metrics *GPUDeviceMetrics) CollectTemperatureMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) CollectTemperatureMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "temperature", func() nvml.Return {
 		temperature, err := handle.GetTemperature(nvml.TEMPERATURE_GPU)
 		if err == nvml.SUCCESS {
 			metrics.GPUTemperature = float64(temperature)
@@ -108,21 +148,69 @@ func (metrics *GPUDeviceMetrics) CollectTemperatureMetrics(ctx context.Context,
 	})
 }
 
-// CollectDeviceIdAsMetric collects the device id as a metric.
-func (metrics *GPUDeviceMetrics) CollectDeviceIdAsMetric(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
-		deviceId, err := handle.GetIndex()
+// temperatureThresholds maps the threshold metrics to the NVML threshold they report.
+var temperatureThresholds = map[config.Metric]nvml.TemperatureThresholds{
+	config.GPU_TEMPERATURE_SHUTDOWN_THRESHOLD: nvml.TEMPERATURE_THRESHOLD_SHUTDOWN,
+	config.GPU_TEMPERATURE_SLOWDOWN_THRESHOLD: nvml.TEMPERATURE_THRESHOLD_SLOWDOWN,
+	config.GPU_TEMPERATURE_MEM_MAX_THRESHOLD:  nvml.TEMPERATURE_THRESHOLD_MEM_MAX,
+	config.GPU_TEMPERATURE_GPU_MAX_THRESHOLD:  nvml.TEMPERATURE_THRESHOLD_GPU_MAX,
+}
+
+// temperatureHeadroom maps the headroom metrics to the threshold they are measured against.
+var temperatureHeadroom = map[config.Metric]nvml.TemperatureThresholds{
+	config.GPU_TEMPERATURE_SHUTDOWN_HEADROOM: nvml.TEMPERATURE_THRESHOLD_SHUTDOWN,
+	config.GPU_TEMPERATURE_SLOWDOWN_HEADROOM: nvml.TEMPERATURE_THRESHOLD_SLOWDOWN,
+}
+
+// collectTemperatureThresholdMetrics collects a temperature threshold in degrees Celsius for the GPU device.
+func (metrics *GPUDeviceMetrics) collectTemperatureThresholdMetrics(ctx context.Context, handle nvml.Device, metric config.Metric, threshold nvml.TemperatureThresholds) error {
+	return WithContext(ctx, metric.GetMetric(), func() nvml.Return {
+		value, err := handle.GetTemperatureThreshold(threshold)
 		if err == nvml.SUCCESS {
-			metrics.DeviceIndex = deviceId
-			SetDeviceMetric(handle, metric, float64(metrics.DeviceIndex))
+			metrics.GPUTemperatureThresholds[metric.GetMetric()] = float64(value)
+			SetDeviceMetric(handle, metric, float64(value))
 		}
 
 		return err
 	})
 }
 
-func (metrics *GPUDeviceMetrics) collectPStateMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+// collectTemperatureHeadroomMetrics collects the degrees Celsius left before the GPU reaches the given threshold.
+func (metrics *GPUDeviceMetrics) collectTemperatureHeadroomMetrics(ctx context.Context, handle nvml.Device, metric config.Metric, threshold nvml.TemperatureThresholds) error {
+	return WithContext(ctx, metric.GetMetric(), func() nvml.Return {
+		limit, err := handle.GetTemperatureThreshold(threshold)
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		temperature, err := handle.GetTemperature(nvml.TEMPERATURE_GPU)
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		headroom := float64(limit) - float64(temperature)
+		metrics.GPUTemperatureHeadroom[metric.GetMetric()] = headroom
+		SetDeviceMetric(handle, metric, headroom)
+
+		return err
+	})
+}
+
+// collectMemoryTemperatureMetrics collects the HBM memory temperature, only reported by data center GPUs.
+func (metrics *GPUDeviceMetrics) collectMemoryTemperatureMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "memory_temperature", func() nvml.Return {
+		temperature, err := getFieldValue(handle, nvml.FI_DEV_MEMORY_TEMP)
+		if err == nvml.SUCCESS {
+			metrics.GPUMemoryTemperature = temperature
+			SetDeviceMetric(handle, metric, temperature)
+		}
+
+		return err
+	})
+}
+
+func (metrics *GPUDeviceMetrics) collectPStateMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "p_state", func() nvml.Return {
 		pState, err := handle.GetPerformanceState()
 		if err == nvml.SUCCESS {
 			metrics.GpuPState = int32(pState)
@@ -135,8 +223,8 @@ func (metrics *GPUDeviceMetrics) collectPStateMetrics(ctx context.Context, handl
 }
 
 // collectMemoryClockMetrics collects the memory clock metrics for the GPU device.
-func (metrics *GPUDeviceMetrics) collectMemoryClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) collectMemoryClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "memory_clock", func() nvml.Return {
 		memoryClock, err := handle.GetClock(nvml.CLOCK_MEM, nvml.CLOCK_ID_CURRENT)
 		if err == nvml.SUCCESS {
 			metrics.GpuClock = memoryClock
@@ -148,8 +236,8 @@ func (metrics *GPUDeviceMetrics) collectMemoryClockMetrics(ctx context.Context,
 }
 
 // collectMemoryClockMetrics collects the memory clock metrics for the GPU device.
-func (metrics *GPUDeviceMetrics) collectGpuClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) collectGpuClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "sm_clock", func() nvml.Return {
 		memoryClock, err := handle.GetClock(nvml.CLOCK_SM, nvml.CLOCK_ID_CURRENT)
 		if err == nvml.SUCCESS {
 			metrics.GpuClock = memoryClock
@@ -160,8 +248,8 @@ func (metrics *GPUDeviceMetrics) collectGpuClockMetrics(ctx context.Context, han
 	})
 }
 
-func (metrics *GPUDeviceMetrics) collectGpuVideoClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) collectGpuVideoClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "video_clock", func() nvml.Return {
 		memoryClock, err := handle.GetClock(nvml.CLOCK_VIDEO, nvml.CLOCK_ID_CURRENT)
 		if err == nvml.SUCCESS {
 			metrics.GpuClock = memoryClock
@@ -172,8 +260,8 @@ func (metrics *GPUDeviceMetrics) collectGpuVideoClockMetrics(ctx context.Context
 	})
 }
 
-func (metrics *GPUDeviceMetrics) collectGpuGraphicsClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+func (metrics *GPUDeviceMetrics) collectGpuGraphicsClockMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "graphics_clock", func() nvml.Return {
 		memoryClock, err := handle.GetClock(nvml.CLOCK_GRAPHICS, nvml.CLOCK_ID_CURRENT)
 		if err == nvml.SUCCESS {
 			metrics.GpuClock = memoryClock
@@ -184,47 +272,278 @@ func (metrics *GPUDeviceMetrics) collectGpuGraphicsClockMetrics(ctx context.Cont
 	})
 }
 
-func (metrics *GPUDeviceMetrics) collectEccCorrectedErrorsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
+// eccCounterTypes maps the counter_type label values to the NVML ECC counter type.
+// Volatile counters reset on driver reload, aggregate counters persist for the lifetime of the GPU.
+var eccCounterTypes = map[string]nvml.EccCounterType{
+	"volatile":  nvml.VOLATILE_ECC,
+	"aggregate": nvml.AGGREGATE_ECC,
+}
+
+// eccErrorTypes maps the error_type label values to the NVML memory error type.
+var eccErrorTypes = map[string]nvml.MemoryErrorType{
+	"corrected":   nvml.MEMORY_ERROR_TYPE_CORRECTED,
+	"uncorrected": nvml.MEMORY_ERROR_TYPE_UNCORRECTED,
+}
 
-	return WithContext(ctx, func() nvml.Return {
-		eccErrors, err := handle.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_CORRECTED, nvml.VOLATILE_ECC)
-		if err == nvml.SUCCESS {
-			metrics.GpuEccErrors = eccErrors
-			SetDeviceMetric(handle, metric, float64(eccErrors))
+// eccMemoryLocations maps the location label values to the NVML memory location.
+var eccMemoryLocations = map[string]nvml.MemoryLocation{
+	"l1_cache":       nvml.MEMORY_LOCATION_L1_CACHE,
+	"l2_cache":       nvml.MEMORY_LOCATION_L2_CACHE,
+	"device_memory":  nvml.MEMORY_LOCATION_DEVICE_MEMORY,
+	"register_file":  nvml.MEMORY_LOCATION_REGISTER_FILE,
+	"texture_memory": nvml.MEMORY_LOCATION_TEXTURE_MEMORY,
+	"sram":           nvml.MEMORY_LOCATION_SRAM,
+}
+
+// collectEccCorrectedErrorsMetrics collects the total corrected ECC errors per counter type.
+func (metrics *GPUDeviceMetrics) collectEccCorrectedErrorsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "ecc_corrected_errors", func() nvml.Return {
+		for counterType, eccCounterType := range eccCounterTypes {
+			eccErrors, err := handle.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_CORRECTED, eccCounterType)
+			if err != nvml.SUCCESS {
+				return err
+			}
+
+			metrics.GpuEccCorrectedErrors[counterType] = eccErrors
+			SetDeviceMetricWithLabels(handle, metric, map[string]string{config.GPU_ECC_COUNTER_TYPE.GetLabel(): counterType}, float64(eccErrors))
 		}
-		return err
+		return nvml.SUCCESS
 	})
 }
 
-func (metrics *GPUDeviceMetrics) collectEccUncorrectedErrorsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
-		eccErrors, err := handle.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.VOLATILE_ECC)
-		if err == nvml.SUCCESS {
-			metrics.GpuEccErrors = eccErrors
-			SetDeviceMetric(handle, metric, float64(eccErrors))
+// collectEccUncorrectedErrorsMetrics collects the total uncorrected ECC errors per counter type.
+func (metrics *GPUDeviceMetrics) collectEccUncorrectedErrorsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "ecc_uncorrected_errors", func() nvml.Return {
+		for counterType, eccCounterType := range eccCounterTypes {
+			eccErrors, err := handle.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, eccCounterType)
+			if err != nvml.SUCCESS {
+				return err
+			}
+
+			metrics.GpuEccUncorrectedErrors[counterType] = eccErrors
+			SetDeviceMetricWithLabels(handle, metric, map[string]string{config.GPU_ECC_COUNTER_TYPE.GetLabel(): counterType}, float64(eccErrors))
 		}
-		return err
+		return nvml.SUCCESS
+	})
+}
+
+// collectEccModeMetrics collects the current and pending ECC mode, the pending mode applies after the next reboot.
+func (metrics *GPUDeviceMetrics) collectEccModeMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "ecc_mode", func() nvml.Return {
+		current, pending, err := handle.GetEccMode()
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		metrics.GpuEccModeEnabled = current == nvml.FEATURE_ENABLED
+		metrics.GpuEccModePendingEnabled = pending == nvml.FEATURE_ENABLED
+
+		if isRegistered(config.GPU_ECC_MODE) {
+			SetDeviceMetric(handle, config.GPU_ECC_MODE, boolToFloat(metrics.GpuEccModeEnabled))
+		}
+
+		if isRegistered(config.GPU_ECC_MODE_PENDING) {
+			SetDeviceMetric(handle, config.GPU_ECC_MODE_PENDING, boolToFloat(metrics.GpuEccModePendingEnabled))
+		}
+
+		return nvml.SUCCESS
+	})
+}
+
+// collectEccLocationErrorsMetrics collects corrected and uncorrected ECC errors per memory location and counter type.
+// Locations not present on the GPU architecture are skipped.
+func (metrics *GPUDeviceMetrics) collectEccLocationErrorsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "ecc_location_errors", func() nvml.Return {
+		current, _, err := handle.GetEccMode()
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		// counters are not maintained while ECC is disabled
+		if current != nvml.FEATURE_ENABLED {
+			return nvml.SUCCESS
+		}
+
+		for errorType, memoryErrorType := range eccErrorTypes {
+			for counterType, eccCounterType := range eccCounterTypes {
+				for location, memoryLocation := range eccMemoryLocations {
+					count, err := handle.GetMemoryErrorCounter(memoryErrorType, eccCounterType, memoryLocation)
+					if err == nvml.ERROR_NOT_SUPPORTED || err == nvml.ERROR_INVALID_ARGUMENT {
+						continue
+					}
+					if err != nvml.SUCCESS {
+						return err
+					}
+
+					eccLabels := map[string]string{
+						config.GPU_ECC_ERROR_TYPE.GetLabel():   errorType,
+						config.GPU_ECC_COUNTER_TYPE.GetLabel(): counterType,
+						config.GPU_MEMORY_LOCATION.GetLabel():  location,
+					}
+					SetDeviceCounterWithLabels(handle, metric, eccLabels, float64(count))
+				}
+			}
+		}
+
+		return nvml.SUCCESS
 	})
 }
 
-// @TODO Fix this not collecting the right value
-func (metrics *GPUDeviceMetrics) collectFanSpeedMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+// collectFanSpeedMetrics collects the speed, target speed and control policy of every fan on the GPU device.
+// Passive cooled GPUs report no fans and are skipped without an error, the target speed and control policy are optional.
+func (metrics *GPUDeviceMetrics) collectFanSpeedMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "fan_speed", func() nvml.Return {
 		fans, err := handle.GetNumFans()
-		if err != nvml.SUCCESS || fans == 0 {
+		if err == nvml.ERROR_NOT_SUPPORTED || (err == nvml.SUCCESS && fans == 0) {
+			return nvml.SUCCESS
+		}
+		if err != nvml.SUCCESS {
 			return err
 		}
-		fanSpeed, err := handle.GetFanSpeed_v2(fans)
-		if err == nvml.SUCCESS {
-			metrics.GpuFanSpeed = fanSpeed
-			SetDeviceMetric(handle, metric, float64(fanSpeed))
+
+		result := nvml.SUCCESS
+		metrics.GpuFanSpeeds = make([]uint32, fans)
+		for fan := 0; fan < fans; fan++ {
+			fanLabels := map[string]string{config.GPU_FAN.GetLabel(): strconv.Itoa(fan)}
+
+			if isRegistered(config.GPU_FAN_SPEED) {
+				fanSpeed, err := handle.GetFanSpeed_v2(fan)
+				if err == nvml.SUCCESS {
+					metrics.GpuFanSpeeds[fan] = fanSpeed
+					SetDeviceMetricWithLabels(handle, config.GPU_FAN_SPEED, fanLabels, float64(fanSpeed))
+				} else {
+					result = err
+				}
+			}
+
+			if isRegistered(config.GPU_FAN_TARGET_SPEED) {
+				targetSpeed, err := handle.GetTargetFanSpeed(fan)
+				if err == nvml.SUCCESS {
+					SetDeviceMetricWithLabels(handle, config.GPU_FAN_TARGET_SPEED, fanLabels, float64(targetSpeed))
+				} else if optional := optionalReturn(err); optional != nvml.SUCCESS {
+					result = optional
+				}
+			}
+
+			if isRegistered(config.GPU_FAN_CONTROL_POLICY) {
+				policy, err := handle.GetFanControlPolicy_v2(fan)
+				if err == nvml.SUCCESS {
+					SetDeviceMetricWithLabels(handle, config.GPU_FAN_CONTROL_POLICY, fanLabels, float64(policy))
+				} else if optional := optionalReturn(err); optional != nvml.SUCCESS {
+					result = optional
+				}
+			}
 		}
-		return err
+
+		return result
+	})
+}
+
+// optionalReturn treats not supported as success for queries that only add detail to a collector,
+// so a device lacking them still reports the other metrics and the collector isn't disabled.
+func optionalReturn(ret nvml.Return) nvml.Return {
+	if ret == nvml.ERROR_NOT_SUPPORTED {
+		return nvml.SUCCESS
+	}
+	return ret
+}
+
+// retiredPageCauses maps the cause label values to the NVML page retirement cause.
+var retiredPageCauses = map[string]nvml.PageRetirementCause{
+	"single_bit_ecc": nvml.PAGE_RETIREMENT_CAUSE_MULTIPLE_SINGLE_BIT_ECC_ERRORS,
+	"double_bit_ecc": nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR,
+}
+
+// collectRetiredPagesMetrics collects the number of retired pages per cause and whether a retirement is pending.
+// Page retirement is replaced by row remapping on Ampere and newer GPUs, the pending status is optional.
+func (metrics *GPUDeviceMetrics) collectRetiredPagesMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "retired_pages", func() nvml.Return {
+		if isRegistered(config.GPU_RETIRED_PAGES) {
+			for cause, retirementCause := range retiredPageCauses {
+				pages, err := handle.GetRetiredPages(retirementCause)
+				if err != nvml.SUCCESS {
+					return err
+				}
+
+				if retirementCause == nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR {
+					metrics.GpuRetiredPagesDoubleBit = len(pages)
+				} else {
+					metrics.GpuRetiredPagesSingleBit = len(pages)
+				}
+				causeLabels := map[string]string{config.GPU_CAUSE.GetLabel(): cause}
+				SetDeviceMetricWithLabels(handle, config.GPU_RETIRED_PAGES, causeLabels, float64(len(pages)))
+			}
+		}
+
+		if isRegistered(config.GPU_RETIRED_PAGES_PENDING) {
+			pending, err := handle.GetRetiredPagesPendingStatus()
+			if err == nvml.SUCCESS {
+				metrics.GpuRetiredPagesPending = pending == nvml.FEATURE_ENABLED
+				SetDeviceMetric(handle, config.GPU_RETIRED_PAGES_PENDING, boolToFloat(metrics.GpuRetiredPagesPending))
+			} else if optional := optionalReturn(err); optional != nvml.SUCCESS {
+				return optional
+			}
+		}
+
+		return nvml.SUCCESS
 	})
 }
 
-func (metrics *GPUDeviceMetrics) collectPeakFlopsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) nvml.Return {
-	return WithContext(ctx, func() nvml.Return {
+// collectRemappedRowsMetrics collects the row remapper state, only supported on Ampere and newer GPUs.
+// The remapper histogram is optional, not all drivers report it.
+// A pending remap needs a GPU reset, a remap failure means the GPU should be replaced.
+func (metrics *GPUDeviceMetrics) collectRemappedRowsMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "remapped_rows", func() nvml.Return {
+		corrected, uncorrected, pending, failure, err := handle.GetRemappedRows()
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		metrics.GpuRemappedRowsCorrected = corrected
+		metrics.GpuRemappedRowsUncorrected = uncorrected
+		metrics.GpuRowRemapPending = pending
+		metrics.GpuRowRemapFailure = failure
+
+		if isRegistered(config.GPU_REMAPPED_ROWS) {
+			SetDeviceMetricWithLabels(handle, config.GPU_REMAPPED_ROWS, map[string]string{config.GPU_CAUSE.GetLabel(): "correctable"}, float64(corrected))
+			SetDeviceMetricWithLabels(handle, config.GPU_REMAPPED_ROWS, map[string]string{config.GPU_CAUSE.GetLabel(): "uncorrectable"}, float64(uncorrected))
+		}
+
+		if isRegistered(config.GPU_ROW_REMAP_PENDING) {
+			SetDeviceMetric(handle, config.GPU_ROW_REMAP_PENDING, boolToFloat(pending))
+		}
+
+		if isRegistered(config.GPU_ROW_REMAP_FAILURE) {
+			SetDeviceMetric(handle, config.GPU_ROW_REMAP_FAILURE, boolToFloat(failure))
+		}
+
+		if isRegistered(config.GPU_ROW_REMAPPER_AVAILABILITY) {
+			histogram, err := handle.GetRowRemapperHistogram()
+			if optional := optionalReturn(err); optional != nvml.SUCCESS {
+				return optional
+			}
+
+			if err == nvml.SUCCESS {
+				// number of memory banks per remaining spare row availability
+				availability := map[string]uint32{
+					"max":     histogram.Max,
+					"high":    histogram.High,
+					"partial": histogram.Partial,
+					"low":     histogram.Low,
+					"none":    histogram.None,
+				}
+				for level, banks := range availability {
+					SetDeviceMetricWithLabels(handle, config.GPU_ROW_REMAPPER_AVAILABILITY, map[string]string{config.GPU_REMAP_AVAILABILITY.GetLabel(): level}, float64(banks))
+				}
+			}
+		}
+
+		return nvml.SUCCESS
+	})
+}
+
+func (metrics *GPUDeviceMetrics) collectPeakFlopsMetrics(ctx context.Context, handle nvml.Device, metric config.Metric) error {
+	return WithContext(ctx, "peak_flops", func() nvml.Return {
 		// Retrieve max clock speed
 		maxClock, err := handle.GetMaxClockInfo(nvml.CLOCK_GRAPHICS)
 		if err != nvml.SUCCESS || maxClock == 0 {
@@ -280,8 +599,45 @@ func (metrics *GPUDeviceMetrics) collectComputeRunningProcesses(handle nvml.Devi
 }
 
 // Additional Metrics can be added here
-//handle.GetActiveVgpus()
 //handle.GetEncoderUtilization()
 //handle.GetDecoderUtilization()
-//handle.GetEccMode()
-//handle.GetTotalEccErrors()
+
+// getFieldValue reads a single NVML field value and converts it to float64.
+func getFieldValue(handle nvml.Device, fieldId uint32) (float64, nvml.Return) {
+	values := []nvml.FieldValue{{FieldId: fieldId}}
+	err := handle.GetFieldValues(values)
+	if err != nvml.SUCCESS {
+		return 0, err
+	}
+
+	if ret := nvml.Return(values[0].NvmlReturn); ret != nvml.SUCCESS {
+		return 0, ret
+	}
+
+	return decodeValue(nvml.ValueType(values[0].ValueType), values[0].Value), nvml.SUCCESS
+}
+
+// decodeValue decodes the raw bytes of an NVML value union according to the reported value type.
+func decodeValue(valueType nvml.ValueType, value [8]byte) float64 {
+	raw := value[:]
+	switch valueType {
+	case nvml.VALUE_TYPE_DOUBLE:
+		return math.Float64frombits(binary.LittleEndian.Uint64(raw))
+	case nvml.VALUE_TYPE_UNSIGNED_INT:
+		return float64(binary.LittleEndian.Uint32(raw))
+	case nvml.VALUE_TYPE_UNSIGNED_LONG, nvml.VALUE_TYPE_UNSIGNED_LONG_LONG:
+		return float64(binary.LittleEndian.Uint64(raw))
+	case nvml.VALUE_TYPE_SIGNED_LONG_LONG:
+		return float64(int64(binary.LittleEndian.Uint64(raw)))
+	default:
+		return 0
+	}
+}
+
+// boolToFloat converts a boolean state to a gauge value of 0 or 1.
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
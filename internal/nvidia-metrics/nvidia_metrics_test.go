@@ -2,11 +2,16 @@ package nvidiametrics
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -23,6 +28,91 @@ func (m *MockNvmlDevice) GetUtilizationRates() (nvml.Utilization, nvml.Return) {
 	return args.Get(0).(nvml.Utilization), args.Get(1).(nvml.Return)
 }
 
+func (m *MockNvmlDevice) GetTemperature(sensor nvml.TemperatureSensors) (uint32, nvml.Return) {
+	args := m.Called(sensor)
+	return args.Get(0).(uint32), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetTemperatureThreshold(threshold nvml.TemperatureThresholds) (uint32, nvml.Return) {
+	args := m.Called(threshold)
+	return args.Get(0).(uint32), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetNumFans() (int, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(int), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetRemappedRows() (int, int, bool, bool, nvml.Return) {
+	args := m.Called()
+	return args.Int(0), args.Int(1), args.Bool(2), args.Bool(3), args.Get(4).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetUUID() (string, nvml.Return) {
+	args := m.Called()
+	return args.String(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetPersistenceMode() (nvml.EnableState, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(nvml.EnableState), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetComputeMode() (nvml.ComputeMode, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(nvml.ComputeMode), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetFanSpeed_v2(fan int) (uint32, nvml.Return) {
+	args := m.Called(fan)
+	return args.Get(0).(uint32), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetTargetFanSpeed(fan int) (int, nvml.Return) {
+	args := m.Called(fan)
+	return args.Int(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetFanControlPolicy_v2(fan int) (nvml.FanControlPolicy, nvml.Return) {
+	args := m.Called(fan)
+	return args.Get(0).(nvml.FanControlPolicy), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetRowRemapperHistogram() (nvml.RowRemapperHistogramValues, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(nvml.RowRemapperHistogramValues), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetRetiredPages(cause nvml.PageRetirementCause) ([]uint64, nvml.Return) {
+	args := m.Called(cause)
+	return args.Get(0).([]uint64), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetRetiredPagesPendingStatus() (nvml.EnableState, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(nvml.EnableState), args.Get(1).(nvml.Return)
+}
+
+// registerTestGauge registers a gauge for the metric with the label names for the current spec,
+// so the collectors set it without loading the metrics config.
+func registerTestGauge(metric config.Metric, labels ...string) *prometheus.GaugeVec {
+	name := metric.GetMetric()
+	gaugeVec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: name}, labels)
+
+	gpuLabels := make(prometheusmetrics.GpuLabels, len(labels))
+	for i, label := range labels {
+		gpuLabels[fmt.Sprintf("label%d", i+1)] = label
+	}
+
+	prometheusmetrics.RegisteredMetrics.AddMetric(name, gaugeVec)
+	prometheusmetrics.RegisteredLabels.AddLabels(name, gpuLabels)
+	DeferCleanup(func() {
+		delete(prometheusmetrics.RegisteredMetrics, name)
+		delete(prometheusmetrics.RegisteredLabels, name)
+	})
+	return gaugeVec
+}
+
 var _ = Describe("GPUDeviceMetrics", func() {
 	var (
 		gpuDeviceMetrics *GPUDeviceMetrics
@@ -48,7 +138,7 @@ var _ = Describe("GPUDeviceMetrics", func() {
 			mockHandle.On("SetDeviceMetric", mock.Anything, config.GPU_MEM_UTILIZATION, 60.0).Return().Once()
 
 			err := gpuDeviceMetrics.CollectUtilizationMetrics(ctx, mockHandle)
-			Expect(err).To(Equal(nvml.SUCCESS))
+			Expect(err).To(Succeed())
 
 			Expect(gpuDeviceMetrics.GPUCPUUtilization).To(Equal(50.0))
 			Expect(gpuDeviceMetrics.GPUMemUtilization).To(Equal(60.0))
@@ -60,7 +150,7 @@ var _ = Describe("GPUDeviceMetrics", func() {
 			mockHandle.On("GetUtilizationRates").Return(nvml.Utilization{}, nvml.ERROR_UNKNOWN).Once()
 
 			err := gpuDeviceMetrics.CollectUtilizationMetrics(ctx, mockHandle)
-			Expect(err).To(Equal(nvml.ERROR_UNKNOWN))
+			Expect(err).To(MatchError(nvml.ERROR_UNKNOWN))
 
 			Expect(gpuDeviceMetrics.GPUCPUUtilization).To(Equal(float64(0)))
 			Expect(gpuDeviceMetrics.GPUMemUtilization).To(Equal(float64(0)))
@@ -68,4 +158,201 @@ var _ = Describe("GPUDeviceMetrics", func() {
 			mockHandle.AssertNotCalled(GinkgoT(), "SetDeviceMetric")
 		})
 	})
+
+	Context("collectTemperatureHeadroomMetrics", func() {
+		It("should report the difference between the threshold and the current temperature", func() {
+			gpuDeviceMetrics = NewGPUDeviceMetrics()
+			mockHandle.On("GetTemperatureThreshold", nvml.TEMPERATURE_THRESHOLD_SLOWDOWN).Return(uint32(90), nvml.SUCCESS).Once()
+			mockHandle.On("GetTemperature", nvml.TEMPERATURE_GPU).Return(uint32(65), nvml.SUCCESS).Once()
+
+			err := gpuDeviceMetrics.collectTemperatureHeadroomMetrics(ctx, mockHandle, config.GPU_TEMPERATURE_SLOWDOWN_HEADROOM, nvml.TEMPERATURE_THRESHOLD_SLOWDOWN)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.GPUTemperatureHeadroom[config.GPU_TEMPERATURE_SLOWDOWN_HEADROOM.GetMetric()]).To(Equal(25.0))
+			mockHandle.AssertExpectations(GinkgoT())
+		})
+
+		It("should not report headroom when the threshold is not supported", func() {
+			gpuDeviceMetrics = NewGPUDeviceMetrics()
+			mockHandle.On("GetTemperatureThreshold", nvml.TEMPERATURE_THRESHOLD_SHUTDOWN).Return(uint32(0), nvml.ERROR_NOT_SUPPORTED).Once()
+
+			err := gpuDeviceMetrics.collectTemperatureHeadroomMetrics(ctx, mockHandle, config.GPU_TEMPERATURE_SHUTDOWN_HEADROOM, nvml.TEMPERATURE_THRESHOLD_SHUTDOWN)
+			Expect(err).To(MatchError(nvml.ERROR_NOT_SUPPORTED))
+
+			Expect(gpuDeviceMetrics.GPUTemperatureHeadroom).To(BeEmpty())
+			mockHandle.AssertNotCalled(GinkgoT(), "GetTemperature", nvml.TEMPERATURE_GPU)
+		})
+	})
+
+	Context("collectFanSpeedMetrics", func() {
+		It("should skip passive cooled GPUs without an error", func() {
+			mockHandle.On("GetNumFans").Return(0, nvml.SUCCESS).Once()
+
+			err := gpuDeviceMetrics.collectFanSpeedMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.GpuFanSpeeds).To(BeEmpty())
+			mockHandle.AssertExpectations(GinkgoT())
+		})
+
+		It("should skip GPUs that do not report a fan count without an error", func() {
+			mockHandle.On("GetNumFans").Return(0, nvml.ERROR_NOT_SUPPORTED).Once()
+
+			err := gpuDeviceMetrics.collectFanSpeedMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+		})
+	})
+
+	Context("collectRemappedRowsMetrics", func() {
+		It("should record the row remapper state", func() {
+			mockHandle.On("GetRemappedRows").Return(2, 1, true, false, nvml.SUCCESS).Once()
+
+			err := gpuDeviceMetrics.collectRemappedRowsMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.GpuRemappedRowsCorrected).To(Equal(2))
+			Expect(gpuDeviceMetrics.GpuRemappedRowsUncorrected).To(Equal(1))
+			Expect(gpuDeviceMetrics.GpuRowRemapPending).To(BeTrue())
+			Expect(gpuDeviceMetrics.GpuRowRemapFailure).To(BeFalse())
+		})
+
+		It("should return not supported on GPUs older than Ampere", func() {
+			mockHandle.On("GetRemappedRows").Return(0, 0, false, false, nvml.ERROR_NOT_SUPPORTED).Once()
+
+			err := gpuDeviceMetrics.collectRemappedRowsMetrics(ctx, mockHandle)
+			Expect(err).To(MatchError(nvml.ERROR_NOT_SUPPORTED))
+		})
+	})
+})
+
+var _ = Describe("WithContext", func() {
+	It("should return nil when the NVML calls succeed", func() {
+		Expect(WithContext(context.Background(), "fan_speed", func() nvml.Return { return nvml.SUCCESS })).To(Succeed())
+	})
+
+	It("should wrap the return code for the collector", func() {
+		err := WithContext(context.Background(), "fan_speed", func() nvml.Return { return nvml.ERROR_NOT_SUPPORTED })
+
+		Expect(nvmlerrors.IsNotSupported(err)).To(BeTrue())
+		Expect(err).To(MatchError(nvml.ERROR_NOT_SUPPORTED))
+		Expect(err.Error()).To(HavePrefix("fan_speed: "))
+	})
+
+	It("should time out without running the NVML calls once the context is done", func() {
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := WithContext(cancelled, "fan_speed", func() nvml.Return {
+			Fail("the NVML calls should not run")
+			return nvml.SUCCESS
+		})
+
+		Expect(nvmlerrors.IsRetryable(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Optional NVML queries", func() {
+	var (
+		gpuDeviceMetrics *GPUDeviceMetrics
+		mockHandle       *MockNvmlDevice
+	)
+
+	BeforeEach(func() {
+		gpuDeviceMetrics = &GPUDeviceMetrics{}
+		mockHandle = new(MockNvmlDevice)
+	})
+
+	Context("collectFanSpeedMetrics", func() {
+		It("should report the fan speed when the target speed and control policy are not supported", func() {
+			fanSpeed := registerTestGauge(config.GPU_FAN_SPEED, config.GPU_FAN.GetLabel())
+			registerTestGauge(config.GPU_FAN_TARGET_SPEED, config.GPU_FAN.GetLabel())
+			registerTestGauge(config.GPU_FAN_CONTROL_POLICY, config.GPU_FAN.GetLabel())
+
+			mockHandle.On("GetNumFans").Return(1, nvml.SUCCESS).Once()
+			mockHandle.On("GetFanSpeed_v2", 0).Return(uint32(40), nvml.SUCCESS).Once()
+			mockHandle.On("GetTargetFanSpeed", 0).Return(0, nvml.ERROR_NOT_SUPPORTED).Once()
+			mockHandle.On("GetFanControlPolicy_v2", 0).Return(nvml.FanControlPolicy(0), nvml.ERROR_NOT_SUPPORTED).Once()
+
+			err := gpuDeviceMetrics.collectFanSpeedMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.GpuFanSpeeds).To(Equal([]uint32{40}))
+			Expect(testutil.ToFloat64(fanSpeed.WithLabelValues("0"))).To(Equal(40.0))
+			mockHandle.AssertExpectations(GinkgoT())
+		})
+
+		It("should return other errors of the control policy", func() {
+			registerTestGauge(config.GPU_FAN_CONTROL_POLICY, config.GPU_FAN.GetLabel())
+
+			mockHandle.On("GetNumFans").Return(1, nvml.SUCCESS).Once()
+			mockHandle.On("GetFanControlPolicy_v2", 0).Return(nvml.FanControlPolicy(0), nvml.ERROR_UNKNOWN).Once()
+
+			err := gpuDeviceMetrics.collectFanSpeedMetrics(ctx, mockHandle)
+			Expect(err).To(MatchError(nvml.ERROR_UNKNOWN))
+		})
+	})
+
+	Context("collectRemappedRowsMetrics", func() {
+		It("should report the row remap state when the histogram is not supported", func() {
+			pending := registerTestGauge(config.GPU_ROW_REMAP_PENDING)
+			failure := registerTestGauge(config.GPU_ROW_REMAP_FAILURE)
+			registerTestGauge(config.GPU_ROW_REMAPPER_AVAILABILITY, config.GPU_REMAP_AVAILABILITY.GetLabel())
+
+			mockHandle.On("GetRemappedRows").Return(0, 0, true, false, nvml.SUCCESS).Once()
+			mockHandle.On("GetRowRemapperHistogram").Return(nvml.RowRemapperHistogramValues{}, nvml.ERROR_NOT_SUPPORTED).Once()
+
+			err := gpuDeviceMetrics.collectRemappedRowsMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(testutil.ToFloat64(pending)).To(Equal(1.0))
+			Expect(testutil.ToFloat64(failure)).To(Equal(0.0))
+			mockHandle.AssertExpectations(GinkgoT())
+		})
+
+		It("should keep the collector enabled when only the histogram is not supported", func() {
+			collectorCapabilities = NewCapabilities()
+			DeferCleanup(func() { collectorCapabilities = NewCapabilities() })
+			registerTestGauge(config.GPU_ROW_REMAPPER_AVAILABILITY, config.GPU_REMAP_AVAILABILITY.GetLabel())
+
+			mockHandle.On("GetRemappedRows").Return(0, 0, false, false, nvml.SUCCESS).Twice()
+			mockHandle.On("GetRowRemapperHistogram").Return(nvml.RowRemapperHistogramValues{}, nvml.ERROR_NOT_SUPPORTED).Twice()
+
+			collect := func() error { return gpuDeviceMetrics.collectRemappedRowsMetrics(ctx, mockHandle) }
+			Expect(observeCollector(5, "GPU-5", "remapped_rows", collect)).To(Succeed())
+			Expect(observeCollector(5, "GPU-5", "remapped_rows", collect)).To(Succeed())
+
+			Expect(collectorCapabilities.Supported("GPU-5")).To(Equal(map[string]bool{"remapped_rows": true}))
+			mockHandle.AssertExpectations(GinkgoT())
+		})
+	})
+
+	Context("collectRetiredPagesMetrics", func() {
+		It("should report the retired pages when the pending status is not supported", func() {
+			retiredPages := registerTestGauge(config.GPU_RETIRED_PAGES, config.GPU_CAUSE.GetLabel())
+			registerTestGauge(config.GPU_RETIRED_PAGES_PENDING)
+
+			mockHandle.On("GetRetiredPages", nvml.PAGE_RETIREMENT_CAUSE_MULTIPLE_SINGLE_BIT_ECC_ERRORS).Return([]uint64{1, 2}, nvml.SUCCESS).Once()
+			mockHandle.On("GetRetiredPages", nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR).Return([]uint64{}, nvml.SUCCESS).Once()
+			mockHandle.On("GetRetiredPagesPendingStatus").Return(nvml.FEATURE_DISABLED, nvml.ERROR_NOT_SUPPORTED).Once()
+
+			err := gpuDeviceMetrics.collectRetiredPagesMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.GpuRetiredPagesSingleBit).To(Equal(2))
+			Expect(testutil.ToFloat64(retiredPages.WithLabelValues("single_bit_ecc"))).To(Equal(2.0))
+			mockHandle.AssertExpectations(GinkgoT())
+		})
+	})
+})
+
+var _ = Describe("NVML values", func() {
+	DescribeTable("decodeValue",
+		func(valueType nvml.ValueType, value [8]byte, expected float64) {
+			Expect(decodeValue(valueType, value)).To(Equal(expected))
+		},
+		Entry("unsigned int", nvml.VALUE_TYPE_UNSIGNED_INT, [8]byte{77, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, 77.0),
+		Entry("unsigned long long", nvml.VALUE_TYPE_UNSIGNED_LONG_LONG, [8]byte{0, 0, 0, 0, 1, 0, 0, 0}, 4294967296.0),
+		Entry("signed long long", nvml.VALUE_TYPE_SIGNED_LONG_LONG, [8]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, -2.0),
+		Entry("double", nvml.VALUE_TYPE_DOUBLE, [8]byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f}, 1.5),
+	)
 })
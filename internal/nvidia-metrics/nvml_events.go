@@ -0,0 +1,265 @@
+package nvidiametrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// eventWaitTimeout is how long a single wait on the event set blocks in milliseconds,
+// it bounds how long the monitor takes to notice the context is done.
+const eventWaitTimeout = 1000
+
+// monitoredEvents maps the NVML event types to the event_type label values.
+var monitoredEvents = map[uint64]string{
+	nvml.EventTypeXidCriticalError:  "xid",
+	nvml.EventTypeSingleBitEccError: "single_bit_ecc",
+	nvml.EventTypeDoubleBitEccError: "double_bit_ecc",
+	nvml.EventTypePowerSourceChange: "power_source_change",
+	nvml.EventTypeClock:             "clock_change",
+}
+
+// deviceSetChanges hands the UUIDs of the current devices from the label cache refresh to the event monitor,
+// it holds only the latest device set.
+var deviceSetChanges = make(chan map[string]bool, 1)
+
+// notifyDeviceSet passes the current device set to the event monitor, replacing a set it didn't read yet.
+func notifyDeviceSet(present map[string]bool) {
+	for {
+		select {
+		case deviceSetChanges <- present:
+			return
+		default:
+		}
+
+		select {
+		case <-deviceSetChanges:
+		default:
+		}
+	}
+}
+
+// eventKey identifies a counted event series for a device.
+type eventKey struct {
+	uuid      string
+	eventType string
+	xid       string
+}
+
+// EventMonitor counts the NVML events delivered to an event set.
+// Events such as XID errors are only delivered to registered event sets and can not be polled.
+type EventMonitor struct {
+	eventSet nvml.EventSet
+	// devices are the UUIDs of the devices the event set was registered for
+	devices map[string]bool
+	counts  map[eventKey]float64
+}
+
+func NewEventMonitor() *EventMonitor {
+	return &EventMonitor{
+		counts: make(map[eventKey]float64),
+	}
+}
+
+// StartEventMonitor registers all devices for the monitored events, again when the device set changes,
+// and counts them in the background until the context is done.
+// The returned channel is closed once the monitor stopped and freed its event set, NVML must not be shut down before.
+func StartEventMonitor(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	if !isRegistered(config.GPU_XID_ERRORS_TOTAL) && !isRegistered(config.GPU_EVENTS_TOTAL) && !isRegistered(config.GPU_LAST_EVENT_TIMESTAMP) {
+		logger.Debug("No event metrics registered, skipping event monitor")
+		close(done)
+		return done
+	}
+
+	addLabelFunctionsOnce.Do(labelManager.AddFunctions)
+
+	monitor := NewEventMonitor()
+	err := monitor.register(ctx)
+	if err != nvml.SUCCESS {
+		logger.Error("Error registering NVML events", zap.Error(err))
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		monitor.run(ctx)
+	}()
+
+	return done
+}
+
+// register creates the event set and registers every device for the events it supports.
+// A previous event set is freed once the new one is registered, it is kept if creating the new one fails.
+func (m *EventMonitor) register(ctx context.Context) nvml.Return {
+	deviceCount, err := CollectGPUDeviceCount(ctx)
+	if err != nil {
+		return nvml.ERROR_UNKNOWN
+	}
+
+	eventSet, ret := nvml.EventSetCreate()
+	if ret != nvml.SUCCESS {
+		return ret
+	}
+
+	var wanted uint64
+	for eventType := range monitoredEvents {
+		wanted |= eventType
+	}
+
+	devices := make(map[string]bool, deviceCount)
+	for i := 0; i < deviceCount; i++ {
+		handle, ret := nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			logger.Error("Error getting device handle", zap.Int("device_index", i), zap.Error(ret))
+			continue
+		}
+
+		if uuid, ret := handle.GetUUID(); ret == nvml.SUCCESS {
+			devices[uuid] = true
+		}
+
+		supported, ret := handle.GetSupportedEventTypes()
+		if ret != nvml.SUCCESS {
+			logger.Warn("Error getting supported event types", zap.Int("device_index", i), zap.Error(ret))
+			continue
+		}
+
+		// register only the supported events, registering an unsupported event fails the whole call
+		ret = handle.RegisterEvents(wanted&supported, eventSet)
+		if ret != nvml.SUCCESS {
+			logger.Warn("Error registering events", zap.Int("device_index", i), zap.Error(ret))
+			continue
+		}
+
+		logger.Info("Registered NVML events", zap.Int("device_index", i), zap.Uint64("event_types", wanted&supported))
+	}
+
+	if m.eventSet != nil {
+		if ret := m.eventSet.Free(); ret != nvml.SUCCESS {
+			logger.Error("Error freeing event set", zap.Error(ret))
+		}
+	}
+	m.eventSet = eventSet
+	m.devices = devices
+
+	return nvml.SUCCESS
+}
+
+// refresh registers the devices again when the device set changed, so added and replaced GPUs report their events,
+// and drops the counts of removed devices.
+func (m *EventMonitor) refresh(ctx context.Context, present map[string]bool) {
+	m.prune(present)
+
+	if sameDevices(m.devices, present) {
+		return
+	}
+
+	logger.Info("GPU device set changed, registering NVML events again", zap.Int("devices", len(present)))
+	if ret := m.register(ctx); ret != nvml.SUCCESS {
+		logger.Error("Error registering NVML events", zap.Error(ret))
+	}
+}
+
+// prune drops the counts of the devices that are no longer present.
+func (m *EventMonitor) prune(present map[string]bool) {
+	for key := range m.counts {
+		if !present[key.uuid] {
+			delete(m.counts, key)
+		}
+	}
+}
+
+// sameDevices reports whether both sets hold the same UUIDs.
+func sameDevices(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for uuid := range a {
+		if !b[uuid] {
+			return false
+		}
+	}
+	return true
+}
+
+// run waits for events until the context is done and frees the event set.
+func (m *EventMonitor) run(ctx context.Context) {
+	defer func() {
+		if ret := m.eventSet.Free(); ret != nvml.SUCCESS {
+			logger.Error("Error freeing event set", zap.Error(ret))
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopped NVML event monitor")
+			return
+		case present := <-deviceSetChanges:
+			m.refresh(ctx, present)
+		default:
+		}
+
+		event, ret := m.eventSet.Wait(eventWaitTimeout)
+		if ret == nvml.ERROR_TIMEOUT {
+			continue
+		}
+
+		if ret != nvml.SUCCESS {
+			logger.Error("Error waiting for NVML events", zap.Error(ret))
+			// back off so a broken event set doesn't spin
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		m.handleEvent(event)
+	}
+}
+
+// handleEvent updates the event metrics for the device that raised the event.
+func (m *EventMonitor) handleEvent(event nvml.EventData) {
+	eventType, ok := monitoredEvents[event.EventType]
+	if !ok || event.Device == nil {
+		return
+	}
+
+	uuid, ret := event.Device.GetUUID()
+	if ret != nvml.SUCCESS {
+		logger.Error("Error getting device uuid for event", zap.String("event_type", eventType), zap.Error(ret))
+		return
+	}
+
+	eventLabels := map[string]string{config.GPU_EVENT_TYPE.GetLabel(): eventType}
+
+	key := eventKey{uuid: uuid, eventType: eventType}
+	m.counts[key]++
+	if isRegistered(config.GPU_EVENTS_TOTAL) {
+		SetDeviceCounterWithLabels(event.Device, config.GPU_EVENTS_TOTAL, eventLabels, m.counts[key])
+	}
+
+	if isRegistered(config.GPU_LAST_EVENT_TIMESTAMP) {
+		SetDeviceMetricWithLabels(event.Device, config.GPU_LAST_EVENT_TIMESTAMP, eventLabels, float64(time.Now().Unix()))
+	}
+
+	if event.EventType == nvml.EventTypeXidCriticalError {
+		xid := strconv.FormatUint(event.EventData, 10)
+		logger.Warn("XID error", zap.String("uuid", uuid), zap.String("xid", xid))
+
+		xidKey := eventKey{uuid: uuid, eventType: eventType, xid: xid}
+		m.counts[xidKey]++
+		if isRegistered(config.GPU_XID_ERRORS_TOTAL) {
+			SetDeviceCounterWithLabels(event.Device, config.GPU_XID_ERRORS_TOTAL, map[string]string{config.GPU_XID.GetLabel(): xid}, m.counts[xidKey])
+		}
+	}
+}
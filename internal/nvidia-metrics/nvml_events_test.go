@@ -0,0 +1,77 @@
+package nvidiametrics
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventMonitor", func() {
+	var (
+		monitor    *EventMonitor
+		mockHandle *MockNvmlDevice
+	)
+
+	BeforeEach(func() {
+		monitor = NewEventMonitor()
+		mockHandle = new(MockNvmlDevice)
+		mockHandle.On("GetUUID").Return("GPU-1234", nvml.SUCCESS)
+	})
+
+	Context("handleEvent", func() {
+		It("should count XID errors per XID code", func() {
+			xid := nvml.EventData{Device: mockHandle, EventType: nvml.EventTypeXidCriticalError, EventData: 79}
+
+			monitor.handleEvent(xid)
+			monitor.handleEvent(xid)
+
+			Expect(monitor.counts[eventKey{uuid: "GPU-1234", eventType: "xid", xid: "79"}]).To(Equal(2.0))
+			Expect(monitor.counts[eventKey{uuid: "GPU-1234", eventType: "xid"}]).To(Equal(2.0))
+		})
+
+		It("should count ECC events without an XID code", func() {
+			monitor.handleEvent(nvml.EventData{Device: mockHandle, EventType: nvml.EventTypeDoubleBitEccError})
+
+			Expect(monitor.counts).To(HaveLen(1))
+			Expect(monitor.counts[eventKey{uuid: "GPU-1234", eventType: "double_bit_ecc"}]).To(Equal(1.0))
+		})
+
+		It("should ignore events that are not monitored", func() {
+			monitor.handleEvent(nvml.EventData{Device: mockHandle, EventType: nvml.EventTypePState})
+
+			Expect(monitor.counts).To(BeEmpty())
+			mockHandle.AssertNotCalled(GinkgoT(), "GetUUID")
+		})
+	})
+
+	Context("prune", func() {
+		It("should drop the counts of removed devices", func() {
+			monitor.counts[eventKey{uuid: "GPU-1234", eventType: "xid", xid: "79"}] = 2
+			monitor.counts[eventKey{uuid: "GPU-5678", eventType: "xid", xid: "79"}] = 1
+
+			monitor.prune(map[string]bool{"GPU-1234": true})
+
+			Expect(monitor.counts).To(HaveLen(1))
+			Expect(monitor.counts).To(HaveKey(eventKey{uuid: "GPU-1234", eventType: "xid", xid: "79"}))
+		})
+	})
+
+	Context("notifyDeviceSet", func() {
+		It("should keep only the latest device set", func() {
+			notifyDeviceSet(map[string]bool{"GPU-1234": true})
+			notifyDeviceSet(map[string]bool{"GPU-5678": true})
+
+			Expect(deviceSetChanges).To(Receive(Equal(map[string]bool{"GPU-5678": true})))
+			Expect(deviceSetChanges).NotTo(Receive())
+		})
+	})
+
+	DescribeTable("sameDevices",
+		func(a, b map[string]bool, expected bool) {
+			Expect(sameDevices(a, b)).To(Equal(expected))
+		},
+		Entry("Same", map[string]bool{"GPU-1234": true}, map[string]bool{"GPU-1234": true}, true),
+		Entry("Replaced", map[string]bool{"GPU-1234": true}, map[string]bool{"GPU-5678": true}, false),
+		Entry("Added", map[string]bool{"GPU-1234": true}, map[string]bool{"GPU-1234": true, "GPU-5678": true}, false),
+	)
+})
@@ -1,6 +1,8 @@
 package nvidiametrics
 
 import (
+	"sync"
+
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
 	"go.uber.org/zap"
@@ -26,24 +28,59 @@ type GpuDevice interface {
 
 // GPUDeviceMetrics represents the collected metrics for a GPU device.
 type GPUDeviceMetrics struct {
-	DeviceIndex         int
-	GPUTemperature      float64
-	GPUCPUUtilization   float64
-	GPUMemUtilization   float64
-	GPUPowerUsage       float64
-	GPURunningProcesses int
-	GPUMemoryUsed       uint64
-	GPUMemoryTotal      uint64
-	GPUMemoryFree       uint64
-	GpuPState           int32
-	GpuClock            uint32
-	GpuEccErrors        uint64
-	GpuFanSpeed         uint32
-	GpuPeakFlops        float64
+	DeviceIndex             int
+	DeviceUUID              string
+	DeviceName              string
+	GPUTemperature          float64
+	GPUCPUUtilization       float64
+	GPUMemUtilization       float64
+	GPUPowerUsage           float64
+	GPURunningProcesses     int
+	GPUMemoryUsed           uint64 // bytes
+	GPUMemoryTotal          uint64 // bytes
+	GPUMemoryFree           uint64 // bytes
+	GPUMemoryReserved       uint64 // bytes
+	GPUBar1MemoryUsed       uint64 // bytes
+	GPUBar1MemoryTotal      uint64 // bytes
+	GPUBar1MemoryFree       uint64 // bytes
+	GpuPState               int32
+	GpuClock                uint32
+	GpuEccCorrectedErrors   map[string]uint64 // keyed by counter type
+	GpuEccUncorrectedErrors map[string]uint64 // keyed by counter type
+	GpuFanSpeeds            []uint32          // indexed by fan
+	GpuPeakFlops            float64
+
+	GPUMemoryTemperature     float64
+	GPUTemperatureThresholds map[string]float64 // keyed by threshold metric name
+	GPUTemperatureHeadroom   map[string]float64 // keyed by headroom metric name
+
+	GpuRetiredPagesSingleBit   int
+	GpuRetiredPagesDoubleBit   int
+	GpuRetiredPagesPending     bool
+	GpuRemappedRowsCorrected   int
+	GpuRemappedRowsUncorrected int
+	GpuRowRemapPending         bool
+	GpuRowRemapFailure         bool
+
+	GpuEccModeEnabled        bool
+	GpuEccModePendingEnabled bool
+
+	MigEnabled bool
+	MigDevices []MigDeviceMetrics
+
+	Vgpus []VgpuMetrics
+
+	UtilizationSummaries map[string]UtilizationSummary // keyed by summary metric name
 }
 
 func NewGPUDeviceMetrics() *GPUDeviceMetrics {
-	return &GPUDeviceMetrics{}
+	return &GPUDeviceMetrics{
+		GPUTemperatureThresholds: make(map[string]float64),
+		GPUTemperatureHeadroom:   make(map[string]float64),
+		GpuEccCorrectedErrors:    make(map[string]uint64),
+		GpuEccUncorrectedErrors:  make(map[string]uint64),
+		UtilizationSummaries:     make(map[string]UtilizationSummary),
+	}
 }
 
 // InitNVML initializes the NVML library.
@@ -51,13 +88,22 @@ func InitNVML() {
 	if err := nvml.Init(); err != nvml.SUCCESS {
 		logger.Fatal("Failed to initialize NVML", zap.Error(err))
 	}
+	collectionStatus.setNvmlInitialized(true)
+	recordBuildInfo()
 	logger.Info("Initialized NVML")
 }
 
-// ShutdownNVML shuts down the NVML library.
+// shutdownOnce makes sure NVML is shut down once, however many paths lead to a shutdown.
+var shutdownOnce sync.Once
+
+// ShutdownNVML shuts down the NVML library, calls after the first one are no-ops.
 func ShutdownNVML() {
-	if err := nvml.Shutdown(); err != nvml.SUCCESS {
-		logger.Fatal("Failed to shutdown NVML", zap.Error(err))
-	}
-	logger.Info("Shutdown NVML")
+	shutdownOnce.Do(func() {
+		collectionStatus.setNvmlInitialized(false)
+		if err := nvml.Shutdown(); err != nvml.SUCCESS {
+			logger.Error("Failed to shutdown NVML", zap.Error(err))
+			return
+		}
+		logger.Info("Shutdown NVML")
+	})
 }
@@ -0,0 +1,163 @@
+package nvidiametrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+)
+
+var collectionScheduler = NewScheduler(0)
+
+// Scheduler tracks when each metric was last collected per device so every metric runs at its own interval.
+// The collection loop ticks at the shortest interval and all collectors share the device handles of that tick.
+type Scheduler struct {
+	mu              sync.Mutex
+	defaultInterval time.Duration
+	tick            time.Duration
+	intervals       map[string]time.Duration
+	lastCollected   map[int]map[string]time.Time
+	lastDeviceSet   time.Time
+	deviceCount     int
+}
+
+// NewScheduler creates a scheduler, metrics without an interval are collected every defaultInterval.
+// A zero defaultInterval collects every metric on every call.
+func NewScheduler(defaultInterval time.Duration) *Scheduler {
+	return &Scheduler{
+		defaultInterval: defaultInterval,
+		tick:            defaultInterval,
+		intervals:       make(map[string]time.Duration),
+		lastCollected:   make(map[int]map[string]time.Time),
+	}
+}
+
+// ConfigureSchedule sets up the collection schedule from the registered metric intervals.
+// It returns the tick the collection loop has to run at.
+func ConfigureSchedule(defaultInterval time.Duration) time.Duration {
+	scheduler := NewScheduler(defaultInterval)
+	for metric, interval := range prometheusmetrics.RegisteredIntervals {
+		scheduler.SetInterval(metric, interval)
+	}
+
+	collectionScheduler = scheduler
+	return scheduler.Tick()
+}
+
+// SetInterval sets the collection interval of a metric and shortens the tick if needed.
+func (s *Scheduler) SetInterval(metric string, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.intervals[metric] = interval
+	if s.tick == 0 || interval < s.tick {
+		s.tick = interval
+	}
+}
+
+// DefaultInterval returns the interval of the metrics without their own interval.
+func (s *Scheduler) DefaultInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaultInterval
+}
+
+// Tick returns the shortest interval of all the metrics.
+func (s *Scheduler) Tick() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tick
+}
+
+// Interval returns the collection interval of a metric.
+func (s *Scheduler) Interval(metric string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval(metric)
+}
+
+func (s *Scheduler) interval(metric string) time.Duration {
+	if interval, ok := s.intervals[metric]; ok {
+		return interval
+	}
+	return s.defaultInterval
+}
+
+// Due reports whether any of the metrics is due on the device and marks them all as collected.
+// Metrics collected together run at the shortest interval among them.
+func (s *Scheduler) Due(deviceIndex int, now time.Time, metrics ...string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, ok := s.lastCollected[deviceIndex]
+	if !ok {
+		last = make(map[string]time.Time)
+		s.lastCollected[deviceIndex] = last
+	}
+
+	due := false
+	for _, metric := range metrics {
+		collected, ok := last[metric]
+		// allow half a tick of jitter so a metric is not pushed back a whole tick
+		if !ok || now.Sub(collected)+s.tick/2 >= s.interval(metric) {
+			due = true
+			break
+		}
+	}
+
+	if due {
+		for _, metric := range metrics {
+			last[metric] = now
+		}
+	}
+
+	return due
+}
+
+// DeviceSetDue reports whether the device set is due to be read again and marks it as read.
+// The device set is read at the default interval, not on every tick, as it takes NVML calls per device,
+// and right away when the device count changed.
+func (s *Scheduler) DeviceSetDue(now time.Time, deviceCount int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.lastDeviceSet.IsZero() && deviceCount == s.deviceCount && now.Sub(s.lastDeviceSet)+s.tick/2 < s.defaultInterval {
+		return false
+	}
+
+	s.lastDeviceSet = now
+	s.deviceCount = deviceCount
+	return true
+}
+
+// isDue reports whether any of the registered metrics is due for collection on the device.
+func isDue(deviceIndex int, metrics ...config.Metric) bool {
+	registered := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		if isRegistered(metric) {
+			registered = append(registered, metric.GetMetric())
+		}
+	}
+
+	if len(registered) == 0 {
+		return false
+	}
+
+	return collectionScheduler.Due(deviceIndex, time.Now(), registered...)
+}
+
+// anyRegistered reports whether one of the metrics is enabled in the metrics config.
+func anyRegistered(metrics ...config.Metric) bool {
+	for _, metric := range metrics {
+		if isRegistered(metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectionInterval returns the interval the metric is collected at, zero if it is collected on every call.
+func CollectionInterval(metric string) time.Duration {
+	return collectionScheduler.Interval(metric)
+}
@@ -0,0 +1,70 @@
+package nvidiametrics
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler", func() {
+	var (
+		scheduler *Scheduler
+		start     time.Time
+	)
+
+	BeforeEach(func() {
+		scheduler = NewScheduler(5 * time.Second)
+		scheduler.SetInterval("gpu_utilization", time.Second)
+		scheduler.SetInterval("gpu_ecc_mode", time.Minute)
+		start = time.Now()
+	})
+
+	It("should tick at the shortest interval", func() {
+		Expect(scheduler.Tick()).To(Equal(time.Second))
+	})
+
+	It("should use the default interval for metrics without one", func() {
+		Expect(scheduler.Interval("gpu_temperature")).To(Equal(5 * time.Second))
+	})
+
+	It("should collect each metric at its own interval", func() {
+		Expect(scheduler.Due(0, start, "gpu_utilization")).To(BeTrue())
+		Expect(scheduler.Due(0, start, "gpu_ecc_mode")).To(BeTrue())
+
+		next := start.Add(time.Second)
+		Expect(scheduler.Due(0, next, "gpu_utilization")).To(BeTrue())
+		Expect(scheduler.Due(0, next, "gpu_ecc_mode")).To(BeFalse())
+
+		Expect(scheduler.Due(0, start.Add(time.Minute), "gpu_ecc_mode")).To(BeTrue())
+	})
+
+	It("should tolerate tick jitter", func() {
+		scheduler.Due(0, start, "gpu_temperature")
+		Expect(scheduler.Due(0, start.Add(4990*time.Millisecond), "gpu_temperature")).To(BeTrue())
+	})
+
+	It("should track devices separately", func() {
+		scheduler.Due(0, start, "gpu_ecc_mode")
+		Expect(scheduler.Due(1, start, "gpu_ecc_mode")).To(BeTrue())
+	})
+
+	It("should read the device set at the default interval", func() {
+		scheduler.SetInterval("gpu_utilization", time.Second)
+
+		Expect(scheduler.DeviceSetDue(start, 2)).To(BeTrue())
+		Expect(scheduler.DeviceSetDue(start.Add(time.Second), 2)).To(BeFalse())
+		Expect(scheduler.DeviceSetDue(start.Add(5*time.Second), 2)).To(BeTrue())
+	})
+
+	It("should read the device set when the device count changed", func() {
+		Expect(scheduler.DeviceSetDue(start, 2)).To(BeTrue())
+		Expect(scheduler.DeviceSetDue(start.Add(time.Second), 1)).To(BeTrue())
+	})
+
+	It("should collect every call without a default interval", func() {
+		scheduler = NewScheduler(0)
+		Expect(scheduler.Due(0, start, "gpu_temperature")).To(BeTrue())
+		Expect(scheduler.Due(0, start, "gpu_temperature")).To(BeTrue())
+	})
+})
@@ -0,0 +1,92 @@
+package nvidiametrics
+
+import (
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+)
+
+// trackedSeries is a series set by a collector, with what is needed to delete it again.
+type trackedSeries struct {
+	metric      string
+	labels      map[string]string // all labels of the series
+	extraLabels map[string]string // the per series labels, the snapshot key
+	uuid        string
+}
+
+// SeriesTracker remembers the series a collector set per device in its last run, so series whose source is gone,
+// e.g. a MIG device after repartitioning or a stopped VM, are deleted instead of reporting their last value forever.
+// A run starts with Begin, sets its series with Set and ends with Sweep once it completed.
+type SeriesTracker struct {
+	mu      sync.Mutex
+	current map[int]map[string]trackedSeries // device index to series key, the series of the last completed run
+	seen    map[int]map[string]trackedSeries // device index to series key, the series of the running run
+}
+
+// NewSeriesTracker creates an empty series tracker.
+func NewSeriesTracker() *SeriesTracker {
+	return &SeriesTracker{
+		current: make(map[int]map[string]trackedSeries),
+		seen:    make(map[int]map[string]trackedSeries),
+	}
+}
+
+// Begin starts a run for the device, forgetting the series of a run that failed halfway.
+func (t *SeriesTracker) Begin(deviceIndex int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seen[deviceIndex] = make(map[string]trackedSeries)
+}
+
+// Set sets the gauge of the device with the per series labels and records the series for the run.
+func (t *SeriesTracker) Set(deviceIndex int, handle nvml.Device, metricConfig config.Metric, extraLabels map[string]string, metricValue float64) {
+	metric := metricConfig.GetMetric()
+	labels := labelManager.GetMetricLabelValuesWith(handle, metric, extraLabels)
+	prometheusmetrics.SetGaugeMetric(metric, labels, metricValue)
+	recordSnapshot(handle, metric, extraLabels, metricValue)
+
+	uuid, _ := labelCache.UUID(handle)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen, ok := t.seen[deviceIndex]
+	if !ok {
+		seen = make(map[string]trackedSeries)
+		t.seen[deviceIndex] = seen
+	}
+	seen[seriesKey(metric, labels)] = trackedSeries{metric: metric, labels: labels, extraLabels: extraLabels, uuid: uuid}
+}
+
+// Sweep ends the run for the device, deleting the series of the last run which weren't set in this one.
+func (t *SeriesTracker) Sweep(deviceIndex int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := t.seen[deviceIndex]
+	for key, series := range t.current[deviceIndex] {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		prometheusmetrics.DeleteGaugeMetric(series.metric, series.labels)
+		snapshots.Delete(series.uuid, series.metric, series.extraLabels)
+	}
+
+	if len(seen) == 0 {
+		delete(t.current, deviceIndex)
+	} else {
+		t.current[deviceIndex] = seen
+	}
+	delete(t.seen, deviceIndex)
+}
+
+// Len returns the number of series of the last completed run of the device.
+func (t *SeriesTracker) Len(deviceIndex int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.current[deviceIndex])
+}
@@ -0,0 +1,199 @@
+package nvidiametrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+)
+
+var snapshots = NewSnapshotStore()
+
+// Snapshots returns the store holding the latest collected state of every device.
+func Snapshots() *SnapshotStore {
+	return snapshots
+}
+
+// MetricSample is the latest value of a single metric series of a device.
+type MetricSample struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value"`
+	Unit      string            `json:"unit,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// DeviceSnapshot is the latest collected state of a device.
+// Metrics run at their own interval, so every sample carries the time it was collected.
+type DeviceSnapshot struct {
+	UUID      string         `json:"uuid"`
+	Index     int            `json:"index"`
+	Name      string         `json:"name"`
+	Timestamp time.Time      `json:"timestamp"`
+	Metrics   []MetricSample `json:"metrics"`
+}
+
+type deviceState struct {
+	uuid      string
+	index     int
+	name      string
+	timestamp time.Time
+	samples   map[string]MetricSample // keyed by series
+}
+
+// SnapshotStore keeps the latest metric values per device UUID, it is safe for concurrent use.
+type SnapshotStore struct {
+	mu      sync.RWMutex
+	devices map[string]*deviceState
+}
+
+func NewSnapshotStore() *SnapshotStore {
+	return &SnapshotStore{devices: make(map[string]*deviceState)}
+}
+
+func (s *SnapshotStore) device(uuid string) *deviceState {
+	state, ok := s.devices[uuid]
+	if !ok {
+		state = &deviceState{uuid: uuid, samples: make(map[string]MetricSample)}
+		s.devices[uuid] = state
+	}
+	return state
+}
+
+// SetCollected records the device identity and the time of its last collection.
+func (s *SnapshotStore) SetCollected(metrics *GPUDeviceMetrics, timestamp time.Time) {
+	if metrics == nil || metrics.DeviceUUID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.device(metrics.DeviceUUID)
+	state.index = metrics.DeviceIndex
+	state.name = metrics.DeviceName
+	state.timestamp = timestamp
+}
+
+// Record stores the latest value of a metric series for the device.
+func (s *SnapshotStore) Record(uuid string, sample MetricSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.device(uuid).samples[seriesKey(sample.Name, sample.Labels)] = sample
+}
+
+// Delete drops a metric series of the device, e.g. of a MIG device that no longer exists.
+func (s *SnapshotStore) Delete(uuid string, name string, labels map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state, ok := s.devices[uuid]; ok {
+		delete(state.samples, seriesKey(name, labels))
+	}
+}
+
+// Retain drops the devices which are no longer present.
+func (s *SnapshotStore) Retain(uuids map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for uuid := range s.devices {
+		if !uuids[uuid] {
+			delete(s.devices, uuid)
+		}
+	}
+}
+
+// Devices returns the snapshots of all devices ordered by device index.
+func (s *SnapshotStore) Devices() []DeviceSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devices := make([]DeviceSnapshot, 0, len(s.devices))
+	for _, state := range s.devices {
+		devices = append(devices, state.snapshot())
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Index < devices[j].Index
+	})
+	return devices
+}
+
+// Device returns the snapshot of the device with the given UUID.
+func (s *SnapshotStore) Device(uuid string) (DeviceSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.devices[uuid]
+	if !ok {
+		return DeviceSnapshot{}, false
+	}
+	return state.snapshot(), true
+}
+
+// snapshot copies the device state ordered by series, so callers can't race with the collection.
+func (d *deviceState) snapshot() DeviceSnapshot {
+	keys := make([]string, 0, len(d.samples))
+	for key := range d.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	samples := make([]MetricSample, 0, len(keys))
+	for _, key := range keys {
+		samples = append(samples, d.samples[key])
+	}
+
+	return DeviceSnapshot{
+		UUID:      d.uuid,
+		Index:     d.index,
+		Name:      d.name,
+		Timestamp: d.timestamp,
+		Metrics:   samples,
+	}
+}
+
+// seriesKey identifies a series by the metric name and its sorted labels.
+func seriesKey(name string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(name)
+	for _, label := range names {
+		key.WriteString("," + label + "=" + labels[label])
+	}
+	return key.String()
+}
+
+// recordSnapshot stores the value set for a device series, devices unknown to the label cache are skipped.
+func recordSnapshot(handle nvml.Device, metric string, extraLabels map[string]string, value float64) {
+	uuid, ok := labelCache.UUID(handle)
+	if !ok {
+		return
+	}
+
+	var labels map[string]string
+	if len(extraLabels) > 0 {
+		labels = make(map[string]string, len(extraLabels))
+		for label, labelValue := range extraLabels {
+			labels[label] = labelValue
+		}
+	}
+
+	snapshots.Record(uuid, MetricSample{
+		Name:      metric,
+		Labels:    labels,
+		Value:     value,
+		Unit:      prometheusmetrics.RegisteredUnits.GetUnit(metric),
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,56 @@
+package nvidiametrics
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SnapshotStore", func() {
+	var (
+		store *SnapshotStore
+		now   time.Time
+	)
+
+	BeforeEach(func() {
+		store = NewSnapshotStore()
+		now = time.Now()
+
+		store.SetCollected(&GPUDeviceMetrics{DeviceIndex: 1, DeviceUUID: "GPU-5678", DeviceName: "NVIDIA A100"}, now)
+		store.SetCollected(&GPUDeviceMetrics{DeviceIndex: 0, DeviceUUID: "GPU-1234", DeviceName: "NVIDIA A100"}, now)
+	})
+
+	It("should list the devices by index", func() {
+		devices := store.Devices()
+		Expect(devices).To(HaveLen(2))
+		Expect(devices[0].UUID).To(Equal("GPU-1234"))
+		Expect(devices[1].UUID).To(Equal("GPU-5678"))
+		Expect(devices[0].Timestamp).To(Equal(now))
+	})
+
+	It("should keep the latest value per series", func() {
+		store.Record("GPU-1234", MetricSample{Name: "gpu_fan_speed", Labels: map[string]string{"fan": "0"}, Value: 30})
+		store.Record("GPU-1234", MetricSample{Name: "gpu_fan_speed", Labels: map[string]string{"fan": "1"}, Value: 40})
+		store.Record("GPU-1234", MetricSample{Name: "gpu_fan_speed", Labels: map[string]string{"fan": "0"}, Value: 35})
+
+		device, ok := store.Device("GPU-1234")
+		Expect(ok).To(BeTrue())
+		Expect(device.Metrics).To(HaveLen(2))
+		Expect(device.Metrics[0].Value).To(Equal(35.0))
+		Expect(device.Metrics[1].Value).To(Equal(40.0))
+	})
+
+	It("should drop devices which are no longer present", func() {
+		store.Retain(map[string]bool{"GPU-1234": true})
+
+		_, ok := store.Device("GPU-5678")
+		Expect(ok).To(BeFalse())
+		Expect(store.Devices()).To(HaveLen(1))
+	})
+
+	It("should skip devices without a UUID", func() {
+		store.SetCollected(&GPUDeviceMetrics{DeviceIndex: 2}, now)
+		Expect(store.Devices()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,64 @@
+package nvidiametrics
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// SystemInfo describes the driver and the GPUs of the host, it is attached to pushed metrics as resource attributes.
+type SystemInfo struct {
+	DriverVersion string
+	NVMLVersion   string
+	DeviceNames   []string // indexed by device index, "unknown" for a device whose name can't be read
+}
+
+// GetSystemInfo reads the driver version and the GPU models, versions are "unknown" while NVML is not initialized.
+// Every device has an entry in DeviceNames, so the index of a name is the index of its device.
+func GetSystemInfo() SystemInfo {
+	info := SystemInfo{DriverVersion: "unknown", NVMLVersion: "unknown"}
+	if !Status().NvmlInitialized {
+		return info
+	}
+
+	info.NVMLVersion, info.DriverVersion = systemVersions()
+
+	deviceCount, ret := nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return info
+	}
+
+	info.DeviceNames = make([]string, deviceCount)
+	for i := range info.DeviceNames {
+		info.DeviceNames[i] = deviceName(i)
+	}
+
+	return info
+}
+
+// deviceName returns the name of the device at the index, "unknown" if it can't be read.
+func deviceName(deviceIndex int) string {
+	handle, ret := nvml.DeviceGetHandleByIndex(deviceIndex)
+	if ret != nvml.SUCCESS {
+		return "unknown"
+	}
+
+	name, ret := handle.GetName()
+	if ret != nvml.SUCCESS {
+		return "unknown"
+	}
+	return name
+}
+
+// systemVersions returns the NVML and driver versions, "unknown" for versions that can't be read.
+func systemVersions() (nvmlVersion string, driverVersion string) {
+	nvmlVersion, ret := nvml.SystemGetNVMLVersion()
+	if ret != nvml.SUCCESS {
+		nvmlVersion = "unknown"
+	}
+
+	driverVersion, ret = nvml.SystemGetDriverVersion()
+	if ret != nvml.SUCCESS {
+		driverVersion = "unknown"
+	}
+
+	return nvmlVersion, driverVersion
+}
@@ -0,0 +1,197 @@
+package nvidiametrics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	prometheusmetrics "github.com/rupeshtr78/nvidia-metrics/internal/prometheus_metrics"
+)
+
+// utilizationSamples maps the summary metrics to the NVML sample buffer they are drained from.
+var utilizationSamples = map[config.Metric]nvml.SamplingType{
+	config.GPU_GPU_UTILIZATION_SUMMARY: nvml.GPU_UTILIZATION_SAMPLES,
+	config.GPU_MEM_UTILIZATION_SUMMARY: nvml.MEMORY_UTILIZATION_SAMPLES,
+}
+
+var lastSamples = newSampleTracker()
+
+// UtilizationSummary summarises the utilization samples NVML recorded since the last read.
+type UtilizationSummary struct {
+	Min     float64
+	Max     float64
+	Avg     float64
+	P95     float64
+	Samples int
+}
+
+type sampleKey struct {
+	device       nvml.Device
+	samplingType nvml.SamplingType
+}
+
+// sampleState is the newest sample read from a buffer and when its summary was reported, zero once it expired.
+type sampleState struct {
+	timestamp uint64
+	updated   time.Time
+}
+
+// sampleTracker remembers the timestamp of the last sample read per device and buffer,
+// so every collection only summarises the samples recorded since the previous one.
+type sampleTracker struct {
+	mu     sync.Mutex
+	states map[sampleKey]sampleState
+}
+
+func newSampleTracker() *sampleTracker {
+	return &sampleTracker{states: make(map[sampleKey]sampleState)}
+}
+
+func (t *sampleTracker) Last(device nvml.Device, samplingType nvml.SamplingType) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.states[sampleKey{device, samplingType}].timestamp
+}
+
+func (t *sampleTracker) Set(device nvml.Device, samplingType nvml.SamplingType, timestamp uint64, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states[sampleKey{device, samplingType}] = sampleState{timestamp: timestamp, updated: now}
+}
+
+// Expire reports whether the summary of the buffer is at least maxAge old and marks it as expired,
+// it reports an expired summary only once.
+func (t *sampleTracker) Expire(device nvml.Device, samplingType nvml.SamplingType, now time.Time, maxAge time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := sampleKey{device, samplingType}
+	state, ok := t.states[key]
+	if !ok || state.updated.IsZero() || now.Sub(state.updated) < maxAge {
+		return false
+	}
+
+	state.updated = time.Time{}
+	t.states[key] = state
+	return true
+}
+
+// Reset forgets all buffers, the handles and sample timestamps of a changed device set don't carry over.
+func (t *sampleTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states = make(map[sampleKey]sampleState)
+}
+
+// collectUtilizationSamplesMetrics drains the NVML utilization sample buffers and reports min, max, avg
+// and p95 over the samples since the last collection, so short bursts are not aliased away by the interval.
+func (metrics *GPUDeviceMetrics) collectUtilizationSamplesMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "utilization_samples", func() nvml.Return {
+		for metric, samplingType := range utilizationSamples {
+			if !isRegistered(metric) {
+				continue
+			}
+
+			last := lastSamples.Last(handle, samplingType)
+			valueType, samples, err := handle.GetSamples(samplingType, last)
+			if err == nvml.ERROR_NOT_FOUND {
+				// no samples recorded since the last read, keep the previous summary for one interval
+				expireUtilizationSummary(handle, metric, samplingType)
+				continue
+			}
+			if err != nvml.SUCCESS {
+				return err
+			}
+
+			summary, newest, ok := summarizeSamples(valueType, samples, last)
+			if !ok {
+				expireUtilizationSummary(handle, metric, samplingType)
+				continue
+			}
+			lastSamples.Set(handle, samplingType, newest, time.Now())
+
+			metrics.UtilizationSummaries[metric.GetMetric()] = summary
+			for stat, value := range summary.Stats() {
+				SetDeviceMetricWithLabels(handle, metric, map[string]string{config.GPU_STAT.GetLabel(): stat}, value)
+			}
+		}
+
+		return nvml.SUCCESS
+	})
+}
+
+// expireUtilizationSummary deletes the summary series of the metric once no new samples were recorded
+// for its interval, so an idle buffer doesn't report the last summary forever.
+func expireUtilizationSummary(handle nvml.Device, metric config.Metric, samplingType nvml.SamplingType) {
+	// allow half a tick of jitter like the scheduler, so the summary is not kept a whole interval longer
+	maxAge := collectionScheduler.Interval(metric.GetMetric()) - collectionScheduler.Tick()/2
+	if !lastSamples.Expire(handle, samplingType, time.Now(), maxAge) {
+		return
+	}
+
+	uuid, _ := labelCache.UUID(handle)
+	for stat := range (UtilizationSummary{}).Stats() {
+		extraLabels := map[string]string{config.GPU_STAT.GetLabel(): stat}
+		labels := labelManager.GetMetricLabelValuesWith(handle, metric.GetMetric(), extraLabels)
+		prometheusmetrics.DeleteGaugeMetric(metric.GetMetric(), labels)
+		snapshots.Delete(uuid, metric.GetMetric(), extraLabels)
+	}
+}
+
+// summarizeSamples summarises the samples newer than the last seen timestamp.
+// It returns the timestamp of the newest sample and false if there are no new samples.
+func summarizeSamples(valueType nvml.ValueType, samples []nvml.Sample, lastSeen uint64) (UtilizationSummary, uint64, bool) {
+	values := make([]float64, 0, len(samples))
+	newest := lastSeen
+	for _, sample := range samples {
+		// NVML buffers are circular and may return samples at the last seen timestamp again
+		if sample.TimeStamp <= lastSeen {
+			continue
+		}
+		values = append(values, decodeValue(valueType, sample.SampleValue))
+		if sample.TimeStamp > newest {
+			newest = sample.TimeStamp
+		}
+	}
+
+	if len(values) == 0 {
+		return UtilizationSummary{}, lastSeen, false
+	}
+
+	sort.Float64s(values)
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+
+	return UtilizationSummary{
+		Min:     values[0],
+		Max:     values[len(values)-1],
+		Avg:     sum / float64(len(values)),
+		P95:     percentile(values, 0.95),
+		Samples: len(values),
+	}, newest, true
+}
+
+// percentile returns the nearest rank percentile of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// Stats returns the summary values keyed by the stat label.
+func (s UtilizationSummary) Stats() map[string]float64 {
+	return map[string]float64{
+		"min": s.Min,
+		"max": s.Max,
+		"avg": s.Avg,
+		"p95": s.P95,
+	}
+}
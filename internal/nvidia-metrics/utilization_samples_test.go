@@ -0,0 +1,93 @@
+package nvidiametrics
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func utilizationSample(timestamp uint64, value uint32) nvml.Sample {
+	sample := nvml.Sample{TimeStamp: timestamp}
+	binary.LittleEndian.PutUint32(sample.SampleValue[:], value)
+	return sample
+}
+
+var _ = Describe("Utilization samples", func() {
+	It("should summarise the samples", func() {
+		samples := []nvml.Sample{
+			utilizationSample(1, 10),
+			utilizationSample(2, 90),
+			utilizationSample(3, 20),
+			utilizationSample(4, 40),
+		}
+
+		summary, newest, ok := summarizeSamples(nvml.VALUE_TYPE_UNSIGNED_INT, samples, 0)
+		Expect(ok).To(BeTrue())
+		Expect(newest).To(Equal(uint64(4)))
+		Expect(summary).To(Equal(UtilizationSummary{Min: 10, Max: 90, Avg: 40, P95: 90, Samples: 4}))
+	})
+
+	It("should skip samples already seen", func() {
+		samples := []nvml.Sample{
+			utilizationSample(2, 90),
+			utilizationSample(3, 20),
+		}
+
+		summary, newest, ok := summarizeSamples(nvml.VALUE_TYPE_UNSIGNED_INT, samples, 2)
+		Expect(ok).To(BeTrue())
+		Expect(newest).To(Equal(uint64(3)))
+		Expect(summary.Samples).To(Equal(1))
+		Expect(summary.Max).To(Equal(20.0))
+	})
+
+	It("should report no summary without new samples", func() {
+		_, newest, ok := summarizeSamples(nvml.VALUE_TYPE_UNSIGNED_INT, []nvml.Sample{utilizationSample(2, 90)}, 2)
+		Expect(ok).To(BeFalse())
+		Expect(newest).To(Equal(uint64(2)))
+	})
+
+	Context("sampleTracker", func() {
+		var (
+			tracker    *sampleTracker
+			mockHandle *MockNvmlDevice
+			now        time.Time
+		)
+
+		BeforeEach(func() {
+			tracker = newSampleTracker()
+			mockHandle = new(MockNvmlDevice)
+			now = time.Now()
+			tracker.Set(mockHandle, nvml.GPU_UTILIZATION_SAMPLES, 42, now)
+		})
+
+		It("should expire the summary once after one interval without new samples", func() {
+			Expect(tracker.Expire(mockHandle, nvml.GPU_UTILIZATION_SAMPLES, now.Add(5*time.Second), 10*time.Second)).To(BeFalse())
+			Expect(tracker.Expire(mockHandle, nvml.GPU_UTILIZATION_SAMPLES, now.Add(10*time.Second), 10*time.Second)).To(BeTrue())
+			Expect(tracker.Expire(mockHandle, nvml.GPU_UTILIZATION_SAMPLES, now.Add(20*time.Second), 10*time.Second)).To(BeFalse())
+			Expect(tracker.Last(mockHandle, nvml.GPU_UTILIZATION_SAMPLES)).To(Equal(uint64(42)))
+		})
+
+		It("should not expire a buffer that was never summarised", func() {
+			Expect(tracker.Expire(mockHandle, nvml.MEMORY_UTILIZATION_SAMPLES, now.Add(time.Minute), 10*time.Second)).To(BeFalse())
+		})
+
+		It("should forget the sample timestamps on reset", func() {
+			tracker.Reset()
+
+			Expect(tracker.Last(mockHandle, nvml.GPU_UTILIZATION_SAMPLES)).To(BeZero())
+			Expect(tracker.Expire(mockHandle, nvml.GPU_UTILIZATION_SAMPLES, now.Add(time.Minute), 10*time.Second)).To(BeFalse())
+		})
+	})
+
+	DescribeTable("percentile",
+		func(values []float64, p float64, expected float64) {
+			Expect(percentile(values, p)).To(Equal(expected))
+		},
+		Entry("single value", []float64{42}, 0.95, 42.0),
+		Entry("nearest rank", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, 0.95, 19.0),
+		Entry("median", []float64{1, 2, 3, 4}, 0.5, 2.0),
+	)
+})
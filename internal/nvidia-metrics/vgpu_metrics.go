@@ -0,0 +1,187 @@
+package nvidiametrics
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+)
+
+// VgpuMetrics represents the collected metrics for an active vGPU instance on a hypervisor host.
+type VgpuMetrics struct {
+	UUID               string
+	VmId               string
+	Type               string
+	FbUsed             uint64
+	EncoderSessions    int
+	SmUtilization      float64
+	MemoryUtilization  float64
+	EncoderUtilization float64
+	DecoderUtilization float64
+}
+
+// vgpuSeries tracks the per vGPU instance series, so the series of stopped VMs are deleted.
+var vgpuSeries = NewSeriesTracker()
+
+// collectVgpuMetrics collects the active vGPU instances of the physical GPU with their framebuffer usage,
+// encoder sessions and utilization. Only supported on hypervisor hosts running the NVIDIA vGPU manager.
+func (metrics *GPUDeviceMetrics) collectVgpuMetrics(ctx context.Context, handle nvml.Device) error {
+	return WithContext(ctx, "vgpu", func() nvml.Return {
+		instances, err := handle.GetActiveVgpus()
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		if isRegistered(config.GPU_VGPU_INSTANCES) {
+			SetDeviceMetric(handle, config.GPU_VGPU_INSTANCES, float64(len(instances)))
+		}
+
+		utilization, err := vgpuUtilization(handle)
+		if err != nvml.SUCCESS {
+			return err
+		}
+
+		vgpuSeries.Begin(metrics.DeviceIndex)
+		metrics.Vgpus = nil
+		for _, instance := range instances {
+			vgpu, err := collectVgpuInstance(instance)
+			if err != nvml.SUCCESS {
+				return err
+			}
+
+			var sample map[string]float64
+			if id, ok := vgpuInstanceId(instance); ok {
+				sample = utilization[id]
+			}
+			if sample != nil {
+				vgpu.SmUtilization = sample[vgpuEngineSm]
+				vgpu.MemoryUtilization = sample[vgpuEngineMemory]
+				vgpu.EncoderUtilization = sample[vgpuEngineEncoder]
+				vgpu.DecoderUtilization = sample[vgpuEngineDecoder]
+			}
+			metrics.Vgpus = append(metrics.Vgpus, vgpu)
+
+			vgpuLabels := map[string]string{
+				config.GPU_VGPU_UUID.GetLabel(): vgpu.UUID,
+				config.GPU_VM_ID.GetLabel():     vgpu.VmId,
+				config.GPU_VGPU_TYPE.GetLabel(): vgpu.Type,
+			}
+
+			if isRegistered(config.GPU_VGPU_FB_USED) {
+				vgpuSeries.Set(metrics.DeviceIndex, handle, config.GPU_VGPU_FB_USED, vgpuLabels, float64(vgpu.FbUsed))
+			}
+
+			if isRegistered(config.GPU_VGPU_ENCODER_SESSIONS) {
+				vgpuSeries.Set(metrics.DeviceIndex, handle, config.GPU_VGPU_ENCODER_SESSIONS, vgpuLabels, float64(vgpu.EncoderSessions))
+			}
+
+			if isRegistered(config.GPU_VGPU_UTILIZATION) {
+				for engine, value := range sample {
+					engineLabels := map[string]string{config.GPU_VGPU_ENGINE.GetLabel(): engine}
+					for k, v := range vgpuLabels {
+						engineLabels[k] = v
+					}
+					vgpuSeries.Set(metrics.DeviceIndex, handle, config.GPU_VGPU_UTILIZATION, engineLabels, value)
+				}
+			}
+		}
+
+		// drop the series of the VMs that stopped since the last collection
+		vgpuSeries.Sweep(metrics.DeviceIndex)
+
+		return nvml.SUCCESS
+	})
+}
+
+// collectVgpuInstance reads the identity, framebuffer usage and encoder sessions of a vGPU instance.
+func collectVgpuInstance(instance nvml.VgpuInstance) (VgpuMetrics, nvml.Return) {
+	var vgpu VgpuMetrics
+	var err nvml.Return
+
+	vgpu.UUID, err = instance.GetUUID()
+	if err != nvml.SUCCESS {
+		return vgpu, err
+	}
+
+	vgpu.VmId, _, err = instance.GetVmID()
+	if err != nvml.SUCCESS {
+		return vgpu, err
+	}
+
+	vgpuType, err := instance.GetType()
+	if err != nvml.SUCCESS {
+		return vgpu, err
+	}
+
+	vgpu.Type, err = vgpuType.GetName()
+	if err != nvml.SUCCESS {
+		return vgpu, err
+	}
+
+	vgpu.FbUsed, err = instance.GetFbUsage()
+	if err != nvml.SUCCESS {
+		return vgpu, err
+	}
+
+	vgpu.EncoderSessions, _, _, err = instance.GetEncoderStats()
+	if err != nvml.SUCCESS {
+		return vgpu, err
+	}
+
+	return vgpu, nvml.SUCCESS
+}
+
+// vGPU utilization engine label values
+const (
+	vgpuEngineSm      = "sm"
+	vgpuEngineMemory  = "memory"
+	vgpuEngineEncoder = "encoder"
+	vgpuEngineDecoder = "decoder"
+)
+
+// vgpuUtilization returns the most recent utilization sample per engine for every vGPU instance on the GPU.
+func vgpuUtilization(handle nvml.Device) (map[uint32]map[string]float64, nvml.Return) {
+	utilization := make(map[uint32]map[string]float64)
+
+	valueType, samples, err := handle.GetVgpuUtilization(0)
+	if err == nvml.ERROR_NOT_FOUND {
+		// no samples buffered yet
+		return utilization, nvml.SUCCESS
+	}
+	if err != nvml.SUCCESS {
+		return nil, err
+	}
+
+	latest := make(map[uint32]uint64)
+	for _, sample := range samples {
+		if sample.TimeStamp < latest[sample.VgpuInstance] {
+			continue
+		}
+		latest[sample.VgpuInstance] = sample.TimeStamp
+		utilization[sample.VgpuInstance] = map[string]float64{
+			vgpuEngineSm:      decodeValue(valueType, sample.SmUtil),
+			vgpuEngineMemory:  decodeValue(valueType, sample.MemUtil),
+			vgpuEngineEncoder: decodeValue(valueType, sample.EncUtil),
+			vgpuEngineDecoder: decodeValue(valueType, sample.DecUtil),
+		}
+	}
+
+	return utilization, nvml.SUCCESS
+}
+
+// vgpuInstanceId returns the NVML id of a vGPU instance, used to match utilization samples to instances.
+// go-nvml has no accessor for the id: GetActiveVgpus returns its unexported nvmlVgpuInstance type, a uint32
+// holding the nvmlVgpuInstance_t id the samples refer to, and the type can't be named outside go-nvml.
+// The dynamic value is converted to uint32 only when it is a uint32, any other representation, e.g. a go-nvml
+// release wrapping the id in a struct, returns false and the instance is reported without utilization.
+// go-nvml is pinned in go.mod for it, and a spec checks the type declaration of the pinned release.
+func vgpuInstanceId(instance nvml.VgpuInstance) (uint32, bool) {
+	value := reflect.ValueOf(instance)
+	if value.Kind() != reflect.Uint32 {
+		return 0, false
+	}
+	return value.Convert(uint32Type).Interface().(uint32), true
+}
+
+var uint32Type = reflect.TypeOf(uint32(0))
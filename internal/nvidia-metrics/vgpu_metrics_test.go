@@ -0,0 +1,191 @@
+package nvidiametrics
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockVgpuInstance is a mock implementation of the nvml.VgpuInstance interface.
+type MockVgpuInstance struct {
+	mock.Mock
+	nvml.VgpuInstance
+}
+
+func (m *MockVgpuInstance) GetUUID() (string, nvml.Return) {
+	args := m.Called()
+	return args.String(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockVgpuInstance) GetVmID() (string, nvml.VgpuVmIdType, nvml.Return) {
+	args := m.Called()
+	return args.String(0), args.Get(1).(nvml.VgpuVmIdType), args.Get(2).(nvml.Return)
+}
+
+func (m *MockVgpuInstance) GetType() (nvml.VgpuTypeId, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(nvml.VgpuTypeId), args.Get(1).(nvml.Return)
+}
+
+func (m *MockVgpuInstance) GetFbUsage() (uint64, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).(uint64), args.Get(1).(nvml.Return)
+}
+
+func (m *MockVgpuInstance) GetEncoderStats() (int, uint32, uint32, nvml.Return) {
+	args := m.Called()
+	return args.Int(0), args.Get(1).(uint32), args.Get(2).(uint32), args.Get(3).(nvml.Return)
+}
+
+// MockVgpuType is a mock implementation of the nvml.VgpuTypeId interface.
+type MockVgpuType struct {
+	mock.Mock
+	nvml.VgpuTypeId
+}
+
+func (m *MockVgpuType) GetName() (string, nvml.Return) {
+	args := m.Called()
+	return args.String(0), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetActiveVgpus() ([]nvml.VgpuInstance, nvml.Return) {
+	args := m.Called()
+	return args.Get(0).([]nvml.VgpuInstance), args.Get(1).(nvml.Return)
+}
+
+func (m *MockNvmlDevice) GetVgpuUtilization(lastSeenTimestamp uint64) (nvml.ValueType, []nvml.VgpuInstanceUtilizationSample, nvml.Return) {
+	args := m.Called(lastSeenTimestamp)
+	return args.Get(0).(nvml.ValueType), args.Get(1).([]nvml.VgpuInstanceUtilizationSample), args.Get(2).(nvml.Return)
+}
+
+// newMockVgpuInstance creates a vGPU instance of the VM with the framebuffer usage.
+func newMockVgpuInstance(uuid string, vmId string, fbUsed uint64) *MockVgpuInstance {
+	vgpuType := new(MockVgpuType)
+	vgpuType.On("GetName").Return("GRID A100-4C", nvml.SUCCESS)
+
+	instance := new(MockVgpuInstance)
+	instance.On("GetUUID").Return(uuid, nvml.SUCCESS)
+	instance.On("GetVmID").Return(vmId, nvml.VGPU_VM_ID_UUID, nvml.SUCCESS)
+	instance.On("GetType").Return(vgpuType, nvml.SUCCESS)
+	instance.On("GetFbUsage").Return(fbUsed, nvml.SUCCESS)
+	instance.On("GetEncoderStats").Return(1, uint32(30), uint32(100), nvml.SUCCESS)
+	return instance
+}
+
+var _ = Describe("vGPU", func() {
+	var (
+		gpuDeviceMetrics *GPUDeviceMetrics
+		mockHandle       *MockNvmlDevice
+	)
+
+	vgpuLabelNames := []string{
+		config.GPU_VGPU_UUID.GetLabel(),
+		config.GPU_VM_ID.GetLabel(),
+		config.GPU_VGPU_TYPE.GetLabel(),
+	}
+
+	BeforeEach(func() {
+		gpuDeviceMetrics = &GPUDeviceMetrics{DeviceIndex: 4}
+		mockHandle = new(MockNvmlDevice)
+
+		vgpuSeries = NewSeriesTracker()
+		DeferCleanup(func() { vgpuSeries = NewSeriesTracker() })
+	})
+
+	Context("collectVgpuMetrics", func() {
+		It("should report the active vGPU instances", func() {
+			instances := registerTestGauge(config.GPU_VGPU_INSTANCES)
+			fbUsed := registerTestGauge(config.GPU_VGPU_FB_USED, vgpuLabelNames...)
+			encoderSessions := registerTestGauge(config.GPU_VGPU_ENCODER_SESSIONS, vgpuLabelNames...)
+
+			mockHandle.On("GetActiveVgpus").Return([]nvml.VgpuInstance{newMockVgpuInstance("vgpu-1", "vm-1", 1<<30)}, nvml.SUCCESS)
+			mockHandle.On("GetVgpuUtilization", uint64(0)).Return(nvml.VALUE_TYPE_UNSIGNED_INT, []nvml.VgpuInstanceUtilizationSample{}, nvml.ERROR_NOT_FOUND)
+
+			err := gpuDeviceMetrics.collectVgpuMetrics(ctx, mockHandle)
+			Expect(err).To(Succeed())
+
+			Expect(gpuDeviceMetrics.Vgpus).To(HaveLen(1))
+			Expect(gpuDeviceMetrics.Vgpus[0].Type).To(Equal("GRID A100-4C"))
+			Expect(testutil.ToFloat64(instances)).To(Equal(1.0))
+			Expect(testutil.ToFloat64(fbUsed.WithLabelValues("vgpu-1", "vm-1", "GRID A100-4C"))).To(Equal(float64(1 << 30)))
+			Expect(testutil.ToFloat64(encoderSessions.WithLabelValues("vgpu-1", "vm-1", "GRID A100-4C"))).To(Equal(1.0))
+		})
+
+		It("should delete the series of stopped VMs", func() {
+			fbUsed := registerTestGauge(config.GPU_VGPU_FB_USED, vgpuLabelNames...)
+
+			mockHandle.On("GetActiveVgpus").Return([]nvml.VgpuInstance{
+				newMockVgpuInstance("vgpu-1", "vm-1", 1<<30),
+				newMockVgpuInstance("vgpu-2", "vm-2", 2<<30),
+			}, nvml.SUCCESS).Once()
+			mockHandle.On("GetActiveVgpus").Return([]nvml.VgpuInstance{
+				newMockVgpuInstance("vgpu-2", "vm-2", 2<<30),
+			}, nvml.SUCCESS).Once()
+			mockHandle.On("GetVgpuUtilization", uint64(0)).Return(nvml.VALUE_TYPE_UNSIGNED_INT, []nvml.VgpuInstanceUtilizationSample{}, nvml.ERROR_NOT_FOUND)
+
+			Expect(gpuDeviceMetrics.collectVgpuMetrics(ctx, mockHandle)).To(Succeed())
+			Expect(testutil.CollectAndCount(fbUsed)).To(Equal(2))
+
+			Expect(gpuDeviceMetrics.collectVgpuMetrics(ctx, mockHandle)).To(Succeed())
+			Expect(testutil.CollectAndCount(fbUsed)).To(Equal(1))
+			Expect(testutil.ToFloat64(fbUsed.WithLabelValues("vgpu-2", "vm-2", "GRID A100-4C"))).To(Equal(float64(2 << 30)))
+		})
+
+		It("should return not supported on hosts without the vGPU manager", func() {
+			mockHandle.On("GetActiveVgpus").Return([]nvml.VgpuInstance{}, nvml.ERROR_NOT_SUPPORTED)
+
+			err := gpuDeviceMetrics.collectVgpuMetrics(ctx, mockHandle)
+			Expect(err).To(MatchError(nvml.ERROR_NOT_SUPPORTED))
+		})
+	})
+
+	Context("vgpuUtilization", func() {
+		It("should keep the latest sample per vGPU instance", func() {
+			mockHandle.On("GetVgpuUtilization", uint64(0)).Return(nvml.VALUE_TYPE_UNSIGNED_INT, []nvml.VgpuInstanceUtilizationSample{
+				{VgpuInstance: 7, TimeStamp: 200, SmUtil: [8]byte{40}},
+				{VgpuInstance: 7, TimeStamp: 100, SmUtil: [8]byte{10}},
+				{VgpuInstance: 8, TimeStamp: 150, MemUtil: [8]byte{25}},
+			}, nvml.SUCCESS)
+
+			utilization, err := vgpuUtilization(mockHandle)
+			Expect(err).To(Equal(nvml.SUCCESS))
+
+			Expect(utilization[7][vgpuEngineSm]).To(Equal(40.0))
+			Expect(utilization[8][vgpuEngineMemory]).To(Equal(25.0))
+		})
+	})
+
+	Context("vgpuInstanceId", func() {
+		It("should not guess the id of an instance that isn't a go-nvml handle", func() {
+			_, ok := vgpuInstanceId(new(MockVgpuInstance))
+			Expect(ok).To(BeFalse())
+		})
+
+		// the handle type can't be created without a driver, its declaration in the go-nvml
+		// sources of the pinned version is checked instead, so an upgrade changing it fails here
+		It("should match the kind of the go-nvml handle", func() {
+			pkg, err := build.Import("github.com/NVIDIA/go-nvml/pkg/nvml", ".", build.FindOnly)
+			Expect(err).NotTo(HaveOccurred())
+
+			file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(pkg.Dir, "types_gen.go"), nil, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			object := file.Scope.Lookup("nvmlVgpuInstance")
+			Expect(object).NotTo(BeNil(), "go-nvml no longer declares nvmlVgpuInstance")
+			Expect(object.Decl).To(BeAssignableToTypeOf(&ast.TypeSpec{}))
+
+			underlying, ok := object.Decl.(*ast.TypeSpec).Type.(*ast.Ident)
+			Expect(ok).To(BeTrue(), "nvmlVgpuInstance is no longer a basic type")
+			Expect(underlying.Name).To(Equal("uint32"))
+		})
+	})
+})
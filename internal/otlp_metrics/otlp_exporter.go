@@ -0,0 +1,166 @@
+// Package otlpmetrics pushes the collected GPU metrics to an OpenTelemetry collector over OTLP.
+// The metrics are read from the Prometheus registry, so OTLP receives the same series as the /metrics endpoint.
+package otlpmetrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/pushurl"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/version"
+	prometheusbridge "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+// OTLP transport protocols, named like OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+// DefaultInterval is the push interval unless configured
+const DefaultInterval = 30 * time.Second
+
+// shutdownTimeout bounds the final push on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// serviceName is the service.name resource attribute of the pushed metrics
+const serviceName = "nvidia-metrics"
+
+// Options configures the OTLP exporter.
+type Options struct {
+	// Endpoint is the URL of the collector, http:// for plaintext, e.g. http://localhost:4317 for gRPC
+	// or http://localhost:4318 for HTTP where the path defaults to /v1/metrics
+	Endpoint string
+	// Protocol is grpc or http/protobuf
+	Protocol string
+	// Interval between pushes, DefaultInterval if zero
+	Interval time.Duration
+	// Headers are sent with every push, e.g. for authentication
+	Headers map[string]string
+	// Gatherer the metrics are read from, the default Prometheus registry if nil
+	Gatherer prometheus.Gatherer
+}
+
+// Exporter pushes the metrics of the Prometheus registry to an OTLP endpoint on an interval.
+type Exporter struct {
+	options Options
+}
+
+// NewExporter validates the options and creates an exporter.
+func NewExporter(options Options) (*Exporter, error) {
+	if options.Protocol != ProtocolGRPC && options.Protocol != ProtocolHTTP {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, use %s or %s", options.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+
+	if err := pushurl.Validate("OTLP endpoint", options.Endpoint); err != nil {
+		return nil, err
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.Gatherer == nil {
+		options.Gatherer = prometheus.DefaultGatherer
+	}
+
+	return &Exporter{options: options}, nil
+}
+
+// Run pushes the metrics every interval until the context is cancelled, then pushes them a last time.
+func (e *Exporter) Run(ctx context.Context) error {
+	// failed pushes are retried on the next interval, only log them
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Error("Failed to push metrics over OTLP", zap.String("endpoint", pushurl.Redact(e.options.Endpoint)), zap.Error(err))
+	}))
+
+	res, err := newResource(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP resource: %w", err)
+	}
+
+	exporter, err := e.newMetricExporter(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(e.options.Interval),
+		sdkmetric.WithProducer(prometheusbridge.NewMetricProducer(prometheusbridge.WithGatherer(e.options.Gatherer))),
+	)
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+
+	logger.Info("Starting OTLP metrics export",
+		zap.String("endpoint", pushurl.Redact(e.options.Endpoint)),
+		zap.String("protocol", e.options.Protocol),
+		zap.Duration("interval", e.options.Interval),
+	)
+
+	<-ctx.Done()
+
+	logger.Info("Stopping OTLP metrics export")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// the collector may be gone on shutdown as well, a failed last push doesn't fail the exporter
+	err = provider.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("Failed to push the last metrics over OTLP", zap.String("endpoint", pushurl.Redact(e.options.Endpoint)), zap.Error(err))
+	}
+	return nil
+}
+
+// newMetricExporter creates the OTLP exporter, the OTEL_EXPORTER_OTLP_* variables configure what the options don't.
+func (e *Exporter) newMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	if e.options.Protocol == ProtocolHTTP {
+		options := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(e.options.Endpoint)}
+		if len(e.options.Headers) > 0 {
+			options = append(options, otlpmetrichttp.WithHeaders(e.options.Headers))
+		}
+		return otlpmetrichttp.New(ctx, options...)
+	}
+
+	options := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpointURL(e.options.Endpoint)}
+	if len(e.options.Headers) > 0 {
+		options = append(options, otlpmetricgrpc.WithHeaders(e.options.Headers))
+	}
+	return otlpmetricgrpc.New(ctx, options...)
+}
+
+// newResource describes the exporter, the host and its GPUs.
+// OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME override the detected attributes.
+func newResource(ctx context.Context) (*resource.Resource, error) {
+	attributes := []attribute.KeyValue{
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version.Version),
+	}
+	attributes = append(attributes, gpuAttributes(nvidiaMetrics.GetSystemInfo())...)
+
+	return resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attributes...),
+		resource.WithFromEnv(),
+	)
+}
+
+// gpuAttributes returns the resource attributes of the driver and the GPU models.
+func gpuAttributes(info nvidiaMetrics.SystemInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("gpu.driver.version", info.DriverVersion),
+		attribute.String("gpu.nvml.version", info.NVMLVersion),
+		attribute.Int("gpu.count", len(info.DeviceNames)),
+		attribute.StringSlice("gpu.model", info.DeviceNames),
+	}
+}
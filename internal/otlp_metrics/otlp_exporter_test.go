@@ -0,0 +1,253 @@
+package otlpmetrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = logger.GetLogger("debug", false, "")
+
+// testReceiver is an OTLP/HTTP receiver answering with status and recording the received export requests.
+type testReceiver struct {
+	status   atomic.Int32
+	mu       sync.Mutex
+	received []*collectormetrics.ExportMetricsServiceRequest
+	headers  []http.Header
+}
+
+func newTestReceiver(t *testing.T) (*testReceiver, *httptest.Server) {
+	t.Helper()
+	receiver := &testReceiver{}
+	receiver.status.Store(http.StatusOK)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read export request: %v", err)
+			return
+		}
+
+		request := &collectormetrics.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, request); err != nil {
+			t.Errorf("Failed to decode export request: %v", err)
+			return
+		}
+		receiver.mu.Lock()
+		receiver.received = append(receiver.received, request)
+		receiver.headers = append(receiver.headers, r.Header.Clone())
+		receiver.mu.Unlock()
+
+		if status := int(receiver.status.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		response, _ := proto.Marshal(&collectormetrics.ExportMetricsServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return receiver, server
+}
+
+func (r *testReceiver) requests() []*collectormetrics.ExportMetricsServiceRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*collectormetrics.ExportMetricsServiceRequest(nil), r.received...)
+}
+
+func (r *testReceiver) requestHeaders() []http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]http.Header(nil), r.headers...)
+}
+
+// newTestExporter creates an exporter pushing a single gauge over OTLP/HTTP.
+func newTestExporter(t *testing.T, endpoint string) (*Exporter, *prometheus.GaugeVec) {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	temperature := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "gpu_temperature", Help: "GPU temperature"}, []string{"gpu_id"})
+	registry.MustRegister(temperature)
+
+	exporter, err := NewExporter(Options{
+		Endpoint: endpoint,
+		Protocol: ProtocolHTTP,
+		Interval: 50 * time.Millisecond,
+		Gatherer: registry,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	return exporter, temperature
+}
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{"grpc", Options{Endpoint: "http://localhost:4317", Protocol: ProtocolGRPC}, false},
+		{"http", Options{Endpoint: "https://otel.example.com:4318", Protocol: ProtocolHTTP}, false},
+		{"unknown protocol", Options{Endpoint: "http://localhost:4317", Protocol: "http/json"}, true},
+		{"missing scheme", Options{Endpoint: "localhost:4317", Protocol: ProtocolGRPC}, true},
+		{"empty endpoint", Options{Protocol: ProtocolGRPC}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := NewExporter(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && exporter.options.Interval != DefaultInterval {
+				t.Errorf("Expected the default interval, got %v", exporter.options.Interval)
+			}
+		})
+	}
+}
+
+func TestExporterRun(t *testing.T) {
+	// Arrange
+	receiver, server := newTestReceiver(t)
+	exporter, temperature := newTestExporter(t, server.URL)
+	temperature.WithLabelValues("0").Set(42)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	// Act
+	go func() {
+		runErr <- exporter.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(receiver.requests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+
+	// Assert
+	requests := receiver.requests()
+	if len(requests) == 0 {
+		t.Fatal("Expected an export request")
+	}
+	request := requests[0]
+	if len(request.ResourceMetrics) != 1 {
+		t.Fatalf("Expected 1 resource, got %d", len(request.ResourceMetrics))
+	}
+	resourceMetrics := request.ResourceMetrics[0]
+
+	attributes := make(map[string]string)
+	for _, kv := range resourceMetrics.Resource.Attributes {
+		attributes[kv.Key] = kv.Value.GetStringValue()
+	}
+	if attributes["service.name"] != serviceName {
+		t.Errorf("Expected service.name %q, got %q", serviceName, attributes["service.name"])
+	}
+	if attributes["host.name"] == "" {
+		t.Error("Expected the host.name resource attribute")
+	}
+	if attributes["gpu.driver.version"] != "unknown" {
+		t.Errorf("Expected an unknown driver version without NVML, got %q", attributes["gpu.driver.version"])
+	}
+
+	found := false
+	for _, scope := range resourceMetrics.ScopeMetrics {
+		for _, metric := range scope.Metrics {
+			if metric.Name != "gpu_temperature" {
+				continue
+			}
+			found = true
+			point := metric.GetGauge().DataPoints[0]
+			if point.GetAsDouble() != 42 {
+				t.Errorf("Expected 42, got %v", point.GetAsDouble())
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected the gpu_temperature metric to be pushed")
+	}
+}
+
+func TestExporterRunSendsHeaders(t *testing.T) {
+	// Arrange
+	receiver, server := newTestReceiver(t)
+	exporter, _ := newTestExporter(t, server.URL)
+	exporter.options.Headers = map[string]string{"Authorization": "Bearer secret"}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act
+	cancel()
+	err := exporter.Run(ctx)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+	headers := receiver.requestHeaders()
+	if len(headers) == 0 {
+		t.Fatal("Expected the metrics to be pushed on shutdown")
+	}
+	if got := headers[0].Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Expected the Authorization header, got %q", got)
+	}
+}
+
+func TestExporterRunIgnoresFailedShutdownPush(t *testing.T) {
+	// Arrange
+	receiver, server := newTestReceiver(t)
+	receiver.status.Store(http.StatusBadRequest)
+	exporter, _ := newTestExporter(t, server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act
+	cancel()
+	err := exporter.Run(ctx)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected a failed last push to be logged only, got %v", err)
+	}
+	if len(receiver.requests()) == 0 {
+		t.Error("Expected the last push to be attempted")
+	}
+}
+
+func TestGpuAttributes(t *testing.T) {
+	info := nvidiaMetrics.SystemInfo{DriverVersion: "550.54.15", NVMLVersion: "12.550.54.15", DeviceNames: []string{"Tesla P40", "Tesla P40"}}
+
+	attributes := make(map[string]any)
+	for _, kv := range gpuAttributes(info) {
+		attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+
+	if attributes["gpu.driver.version"] != "550.54.15" {
+		t.Errorf("Expected the driver version, got %v", attributes["gpu.driver.version"])
+	}
+	if attributes["gpu.count"] != int64(2) {
+		t.Errorf("Expected 2 GPUs, got %v", attributes["gpu.count"])
+	}
+}
@@ -2,9 +2,12 @@ package prometheusmetrics
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rupeshtr78/nvidia-metrics/internal/config"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
 )
 
 type Metrics struct {
@@ -12,10 +15,12 @@ type Metrics struct {
 }
 
 type GpuMetric struct {
-	Name   config.Metric `yaml:"name"`
-	Help   string        `yaml:"help"`
-	Type   string        `yaml:"type"`
-	Labels GpuLabels     `yaml:"labels"` // {label1: gpu_id, label2: gpu_name}
+	Name     config.Metric `yaml:"name"`
+	Help     string        `yaml:"help"`
+	Type     string        `yaml:"type"`
+	Labels   GpuLabels     `yaml:"labels"`   // {label1: gpu_id, label2: gpu_name}
+	Unit     string        `yaml:"unit"`     // base unit e.g. bytes, seconds; the name has to end with _<unit>
+	Interval string        `yaml:"interval"` // collection interval e.g. 1s, 60s, 10m; defaults to the global interval
 }
 
 type GpuLabels map[string]string
@@ -23,9 +28,18 @@ type GpuLabels map[string]string
 // Labels for the metrics
 type LabelsMap map[string]GpuLabels
 
+// Units for the metrics
+type UnitsMap map[string]string
+
+// Collection intervals for the metrics
+type IntervalsMap map[string]time.Duration
+
 // Metrics for the GPU
 type MetricMap map[string]*prometheus.GaugeVec
 
+// Counter metrics for the GPU
+type CounterMap map[string]*prometheus.CounterVec
+
 // CreateLabelsMap creates a new LabelsMap
 func CreateLabelsMap() LabelsMap {
 	l := make(LabelsMap)
@@ -43,6 +57,75 @@ func (l *LabelsMap) GetLabelsFromMap(metricName string) (GpuLabels, error) {
 	return nil, fmt.Errorf("labels %v not found in map", metricName)
 }
 
+// CreateUnitsMap creates a new UnitsMap
+func CreateUnitsMap() UnitsMap {
+	u := make(UnitsMap)
+	return u
+}
+
+func (u *UnitsMap) AddUnit(metricName string, unit string) {
+	(*u)[metricName] = unit
+}
+
+// GetUnit returns the unit declared for the metric, empty if none was declared
+func (u *UnitsMap) GetUnit(metricName string) string {
+	return (*u)[metricName]
+}
+
+// ValidateUnit checks the metric name follows the prometheus unit suffix convention for the declared unit.
+// Example: unit bytes requires gpu_memory_used_bytes, or gpu_read_bytes_total for counters
+func ValidateUnit(gpuMetric GpuMetric) error {
+	if gpuMetric.Unit == "" {
+		return nil
+	}
+
+	name := gpuMetric.Name.GetMetric()
+	suffix := "_" + gpuMetric.Unit
+	if gpuMetric.Type == "counter" {
+		suffix += "_total"
+	}
+
+	if !strings.HasSuffix(name, suffix) {
+		return fmt.Errorf("metric %v with unit %v must end with %v", name, gpuMetric.Unit, suffix)
+	}
+
+	return nil
+}
+
+// CreateIntervalsMap creates a new IntervalsMap
+func CreateIntervalsMap() IntervalsMap {
+	i := make(IntervalsMap)
+	return i
+}
+
+func (i *IntervalsMap) AddInterval(metricName string, interval time.Duration) {
+	(*i)[metricName] = interval
+}
+
+// GetInterval returns the collection interval declared for the metric, false if none was declared
+func (i *IntervalsMap) GetInterval(metricName string) (time.Duration, bool) {
+	interval, ok := (*i)[metricName]
+	return interval, ok
+}
+
+// ParseInterval parses the collection interval declared for the metric, zero if none was declared
+func ParseInterval(gpuMetric GpuMetric) (time.Duration, error) {
+	if gpuMetric.Interval == "" {
+		return 0, nil
+	}
+
+	interval, err := time.ParseDuration(gpuMetric.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("metric %v has invalid interval %v: %v", gpuMetric.Name, gpuMetric.Interval, err)
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("metric %v interval must be positive, got %v", gpuMetric.Name, gpuMetric.Interval)
+	}
+
+	return interval, nil
+}
+
 func CreateMetricsMap() MetricMap {
 	m := make(MetricMap)
 	return m
@@ -56,5 +139,21 @@ func (m *MetricMap) GetMetric(metricName string) (*prometheus.GaugeVec, error) {
 	if metric, ok := (*m)[metricName]; ok {
 		return metric, nil
 	}
-	return nil, fmt.Errorf("skipping metric: %v not found in config map", metricName)
+	return nil, fmt.Errorf("skipping metric: %w", nvmlerrors.MetricNotRegistered(metricName))
+}
+
+func CreateCounterMap() CounterMap {
+	c := make(CounterMap)
+	return c
+}
+
+func (c *CounterMap) AddCounter(metricName string, metric *prometheus.CounterVec) {
+	(*c)[metricName] = metric
+}
+
+func (c *CounterMap) GetCounter(metricName string) (*prometheus.CounterVec, error) {
+	if metric, ok := (*c)[metricName]; ok {
+		return metric, nil
+	}
+	return nil, fmt.Errorf("skipping counter: %w", nvmlerrors.MetricNotRegistered(metricName))
 }
@@ -3,6 +3,7 @@ package prometheusmetrics
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	"testing"
+	"time"
 )
 
 func labelsHelper(t *testing.T) (labels GpuLabels, actual LabelsMap) {
@@ -127,3 +128,65 @@ func TestMetricMap_GetMetric_InvalidMetric(t *testing.T) {
 		t.Errorf("Expected %v, got %v", expected, got)
 	}
 }
+
+func TestValidateUnit(t *testing.T) {
+	tests := []struct {
+		name        string
+		gpuMetric   GpuMetric
+		expectError bool
+	}{
+		{"NoUnit", GpuMetric{Name: "gpu_temperature", Type: "gauge"}, false},
+		{"GaugeWithSuffix", GpuMetric{Name: "gpu_memory_used_bytes", Type: "gauge", Unit: "bytes"}, false},
+		{"GaugeWithoutSuffix", GpuMetric{Name: "gpu_memory_used", Type: "gauge", Unit: "bytes"}, true},
+		{"CounterWithSuffix", GpuMetric{Name: "gpu_read_bytes_total", Type: "counter", Unit: "bytes"}, false},
+		{"CounterWithoutTotal", GpuMetric{Name: "gpu_read_bytes", Type: "counter", Unit: "bytes"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := ValidateUnit(tt.gpuMetric)
+
+			// Assert
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for %v", tt.gpuMetric.Name)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for %v, got %v", tt.gpuMetric.Name, err)
+			}
+		})
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		gpuMetric   GpuMetric
+		expected    time.Duration
+		expectError bool
+	}{
+		{"NoInterval", GpuMetric{Name: "gpu_temperature"}, 0, false},
+		{"Seconds", GpuMetric{Name: "gpu_ecc_mode", Interval: "60s"}, 60 * time.Second, false},
+		{"Minutes", GpuMetric{Name: "gpu_info", Interval: "10m"}, 10 * time.Minute, false},
+		{"Invalid", GpuMetric{Name: "gpu_info", Interval: "often"}, 0, true},
+		{"Negative", GpuMetric{Name: "gpu_info", Interval: "-1s"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			interval, err := ParseInterval(tt.gpuMetric)
+
+			// Assert
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for %v", tt.gpuMetric.Interval)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for %v, got %v", tt.gpuMetric.Interval, err)
+			}
+			if interval != tt.expected {
+				t.Errorf("Expected interval %v, got %v", tt.expected, interval)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package prometheusmetrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// counterTotal is the last running total set for a counter series.
+type counterTotal struct {
+	name   string
+	labels GpuLabels
+	total  float64
+}
+
+// counterTotals keeps the last total set for each counter series, keyed by counterKey.
+// NVML reports running totals while prometheus counters can only be incremented.
+var (
+	counterTotals   = make(map[string]counterTotal)
+	counterTotalsMu sync.Mutex
+)
+
+// CreateCounter sets the counter with labels to the given running total
+func CreateCounter(name string, labels GpuLabels, total float64) error {
+	if RegisteredCounters == nil {
+		logger.Error("Counters map is nil")
+		return fmt.Errorf("counters map is nil")
+	}
+
+	counterVec, err := RegisteredCounters.GetCounter(name)
+	if err != nil {
+		logger.Warn("Failed to get counter from counters map", zap.Error(err))
+		return nil
+	}
+
+	// get prometheus labels
+	gpuLabels, err := GetPromtheusLabels(labels)
+	if err != nil {
+		logger.Error("Failed to get prometheues labels", zap.Error(err))
+		return err
+	}
+
+	counterTotalsMu.Lock()
+	defer counterTotalsMu.Unlock()
+
+	key := counterKey(name, labels)
+	last := counterTotals[key].total
+	if total < last {
+		// The total went backwards, e.g. volatile counters after a driver reload, start the series over
+		counterVec.Delete(gpuLabels)
+		last = 0
+	}
+
+	counterVec.With(gpuLabels).Add(total - last)
+	counterTotals[key] = counterTotal{name: name, labels: labels, total: total}
+
+	logger.Debug("Created the counter", zap.String("name", name), zap.Any("labels", labels), zap.Float64("total", total))
+
+	return nil
+}
+
+// SetCounterMetric sets a counter metric with the given name, labels, and running total.
+func SetCounterMetric(name string, labels GpuLabels, total float64) {
+	err := CreateCounter(name, labels, total)
+	if err != nil {
+		logger.Error("Failed to create counter metric", zap.String("metric_name", name), zap.Error(err))
+	}
+}
+
+// DeleteCounterSeries removes the counter series whose labels include all given labels, e.g. the series of a
+// removed device, and forgets their totals so a new device at the same index starts its series over.
+func DeleteCounterSeries(match GpuLabels) {
+	counterTotalsMu.Lock()
+	defer counterTotalsMu.Unlock()
+
+	for _, counterVec := range RegisteredCounters {
+		counterVec.DeletePartialMatch(prometheus.Labels(match))
+	}
+
+	for key, series := range counterTotals {
+		if matchesLabels(series.labels, match) {
+			delete(counterTotals, key)
+		}
+	}
+}
+
+// matchesLabels reports whether labels include all labels of match.
+func matchesLabels(labels GpuLabels, match GpuLabels) bool {
+	for name, value := range match {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// counterKey builds a stable key for a counter series from its name and labels.
+func counterKey(name string, labels GpuLabels) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,68 @@
+package prometheusmetrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = logger.GetLogger("debug", false, "")
+
+func TestCreateCounter(t *testing.T) {
+	// Assign
+	counterVec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "gpu_counter_test_total"}, []string{"gpu_id"})
+	RegisteredCounters.AddCounter("gpu_counter_test_total", counterVec)
+	labels := GpuLabels{"gpu_id": "0"}
+
+	tests := []struct {
+		name  string
+		total float64
+		want  float64
+	}{
+		{"FirstTotal", 5, 5},
+		{"IncreasedTotal", 8, 8},
+		{"UnchangedTotal", 8, 8},
+		{"ResetTotal", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := CreateCounter("gpu_counter_test_total", labels, tt.total)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, testutil.ToFloat64(counterVec.WithLabelValues("0")))
+		})
+	}
+}
+
+func TestCreateCounter_NotRegistered(t *testing.T) {
+	err := CreateCounter("gpu_counter_missing_total", GpuLabels{"gpu_id": "0"}, 1)
+	assert.NoError(t, err)
+}
+
+func TestDeleteCounterSeries(t *testing.T) {
+	// Assign
+	counterVec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "gpu_counter_delete_test_total"}, []string{"gpu_id", "counter_type"})
+	RegisteredCounters.AddCounter("gpu_counter_delete_test_total", counterVec)
+	t.Cleanup(func() { delete(RegisteredCounters, "gpu_counter_delete_test_total") })
+
+	assert.NoError(t, CreateCounter("gpu_counter_delete_test_total", GpuLabels{"gpu_id": "0", "counter_type": "volatile"}, 5))
+	assert.NoError(t, CreateCounter("gpu_counter_delete_test_total", GpuLabels{"gpu_id": "1", "counter_type": "volatile"}, 7))
+
+	// Act
+	DeleteCounterSeries(GpuLabels{"gpu_id": "1"})
+
+	// Assert
+	assert.Equal(t, 1, testutil.CollectAndCount(counterVec))
+	_, ok := counterTotals[counterKey("gpu_counter_delete_test_total", GpuLabels{"gpu_id": "1", "counter_type": "volatile"})]
+	assert.False(t, ok, "the total of the deleted series should be forgotten")
+
+	// a new device at the index starts its series over from its own total
+	assert.NoError(t, CreateCounter("gpu_counter_delete_test_total", GpuLabels{"gpu_id": "1", "counter_type": "volatile"}, 9))
+	assert.Equal(t, 9.0, testutil.ToFloat64(counterVec.WithLabelValues("1", "volatile")))
+}
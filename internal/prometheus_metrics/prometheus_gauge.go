@@ -3,6 +3,7 @@ package prometheusmetrics
 import (
 	"fmt"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
 	"go.uber.org/zap"
 )
@@ -47,3 +48,16 @@ func SetGaugeMetric(name string, labels GpuLabels, value float64) {
 		logger.Error("Failed to create gauge metric", zap.String("metric_name", string(name)), zap.Error(err))
 	}
 }
+
+// DeleteGaugeMetric removes the gauge series with the given name and labels.
+func DeleteGaugeMetric(name string, labels GpuLabels) {
+	gaugeVec, err := RegisteredMetrics.GetMetric(name)
+	if err != nil {
+		logger.Warn("Failed to get metric from metrics map", zap.Error(err))
+		return
+	}
+
+	if !gaugeVec.Delete(prometheus.Labels(labels)) {
+		logger.Debug("Gauge series not found", zap.String("name", name), zap.Any("labels", labels))
+	}
+}
@@ -2,7 +2,9 @@ package prometheusmetrics
 
 import (
 	"fmt"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/nvmlerrors"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/utils"
 )
 
@@ -37,7 +39,7 @@ func GetLabelsForMetric(metricName string, filePath string) ([]string, error) {
 
 	// check if the metric exists in prometheus
 	if _, ok := RegisteredMetrics[metricName]; !ok {
-		return nil, fmt.Errorf("metric %v not registered", metricName)
+		return nil, nvmlerrors.MetricNotRegistered(metricName)
 	}
 
 	// read from config/metrics.yaml
@@ -64,6 +66,6 @@ func GetLabelsForMetric(metricName string, filePath string) ([]string, error) {
 
 	}
 
-	return nil, fmt.Errorf("")
+	return nil, fmt.Errorf("metric not found in the yaml file %v: %w", filePath, nvmlerrors.MetricNotRegistered(metricName))
 
 }
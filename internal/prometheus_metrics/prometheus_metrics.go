@@ -3,6 +3,7 @@ package prometheusmetrics
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
@@ -11,7 +12,18 @@ import (
 )
 
 var RegisteredMetrics = CreateMetricsMap()
+var RegisteredCounters = CreateCounterMap()
 var RegisteredLabels = CreateLabelsMap()
+var RegisteredUnits = CreateUnitsMap()
+var RegisteredIntervals = CreateIntervalsMap()
+
+// configLoaded is set once the metrics config has been loaded and all metrics are registered
+var configLoaded atomic.Bool
+
+// ConfigLoaded reports whether the metrics config has been loaded and all metrics are registered.
+func ConfigLoaded() bool {
+	return configLoaded.Load()
+}
 
 // RegisterMetric NewGaugeVec creates a new gauge vector and registers it with Prometheus.
 func RegisterMetric(ctx context.Context, gpuMetric GpuMetric) (*prometheus.GaugeVec, error) {
@@ -58,8 +70,65 @@ func RegisterMetric(ctx context.Context, gpuMetric GpuMetric) (*prometheus.Gauge
 	return gaugeVec, nil
 }
 
+// RegisterCounterMetric creates a new counter vector and registers it with Prometheus.
+func RegisterCounterMetric(ctx context.Context, gpuMetric GpuMetric) (*prometheus.CounterVec, error) {
+	if gpuMetric.Type != "counter" {
+		err := fmt.Errorf("unsupported metric type: %s", gpuMetric.Type)
+		logger.Error("unsupported metric type", zap.String("type", gpuMetric.Type))
+		return nil, err
+	}
+
+	labels, err := GetGPuLabels(gpuMetric.Labels)
+	if err != nil {
+		logger.Error("failed to get labels", zap.Error(err))
+		return nil, err
+	}
+
+	// Create a new counter vector
+	counterVec := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: gpuMetric.Name.GetMetric(),
+			Help: gpuMetric.Help,
+		},
+		labels,
+	)
+
+	// Unregister first; if not registered, no operations will be performed
+	if !prometheus.Unregister(counterVec) {
+		logger.Warn("metric was already registered", zap.String("metric", gpuMetric.Name.GetMetric()))
+	}
+
+	// Register the metric with Prometheus
+	select {
+	case <-ctx.Done():
+		logger.Error("context cancelled", zap.String("metric", gpuMetric.Name.GetMetric()))
+		return nil, ctx.Err()
+	default:
+		err = prometheus.Register(counterVec)
+		if err != nil {
+			logger.Error("failed to register metric", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	logger.Info("Verified registration of", zap.String("metric", gpuMetric.Name.GetMetric()))
+
+	return counterVec, nil
+}
+
 // CreatePrometheusMetrics reads from config/metrics.yaml and create prometheus metrics
+// The outcome of the load is exported as gpu_metrics_config_last_load_success.
 func CreatePrometheusMetrics(ctx context.Context, filePath string) error {
+	err := createPrometheusMetrics(ctx, filePath)
+	recordConfigLoad(err)
+	if err != nil {
+		return err
+	}
+
+	return registerExporterCollector(newActiveSeriesCollector())
+}
+
+func createPrometheusMetrics(ctx context.Context, filePath string) error {
 	var m Metrics
 	// 	// read from config/metrics.yaml
 	err := utils.LoadFromYAMLV2(filePath, &m)
@@ -74,6 +143,33 @@ func CreatePrometheusMetrics(ctx context.Context, filePath string) error {
 
 	// create prometheus metrics from yaml
 	for _, metric := range m.MetricList {
+		if err := ValidateUnit(metric); err != nil {
+			logger.Error("invalid metric unit", zap.Error(err))
+			return err
+		}
+		RegisteredUnits.AddUnit(metric.Name.GetMetric(), metric.Unit)
+
+		interval, err := ParseInterval(metric)
+		if err != nil {
+			logger.Error("invalid metric interval", zap.Error(err))
+			return err
+		}
+		if interval > 0 {
+			RegisteredIntervals.AddInterval(metric.Name.GetMetric(), interval)
+		}
+
+		if metric.Type == "counter" {
+			counterVec, err := RegisterCounterMetric(ctx, metric)
+			if err != nil {
+				return err
+			}
+
+			// Add the metric to the counters map
+			RegisteredCounters.AddCounter(metric.Name.GetMetric(), counterVec)
+			RegisteredLabels.AddLabels(metric.Name.GetMetric(), metric.Labels)
+			continue
+		}
+
 		gaugeVec, err := RegisterMetric(ctx, metric)
 		if err != nil {
 			return err
@@ -85,5 +181,6 @@ func CreatePrometheusMetrics(ctx context.Context, filePath string) error {
 
 	}
 
+	configLoaded.Store(true)
 	return nil
 }
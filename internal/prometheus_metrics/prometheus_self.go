@@ -0,0 +1,89 @@
+package prometheusmetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	configLastLoadSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gpu_metrics_config_last_load_success",
+		Help: "1 if the last load of the metrics config succeeded.",
+	})
+
+	configLastLoadSuccessTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gpu_metrics_config_last_load_success_timestamp_seconds",
+		Help: "The time of the last successful load of the metrics config.",
+	})
+)
+
+// recordConfigLoad exports the outcome of loading the metrics config.
+func recordConfigLoad(err error) {
+	if err != nil {
+		configLastLoadSuccess.Set(0)
+		return
+	}
+
+	configLastLoadSuccess.Set(1)
+	configLastLoadSuccessTimestamp.Set(float64(time.Now().Unix()))
+}
+
+// activeSeriesCollector exports the number of series of every registered metric when scraped.
+type activeSeriesCollector struct {
+	desc *prometheus.Desc
+}
+
+func newActiveSeriesCollector() *activeSeriesCollector {
+	return &activeSeriesCollector{
+		desc: prometheus.NewDesc(
+			"gpu_metrics_active_series",
+			"The number of series exported per metric.",
+			[]string{"metric"},
+			nil,
+		),
+	}
+}
+
+func (c *activeSeriesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *activeSeriesCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, gaugeVec := range RegisteredMetrics {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(countSeries(gaugeVec)), name)
+	}
+	for name, counterVec := range RegisteredCounters {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(countSeries(counterVec)), name)
+	}
+}
+
+// countSeries counts the series the collector currently exports.
+func countSeries(collector prometheus.Collector) int {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(metrics)
+		close(metrics)
+	}()
+
+	count := 0
+	for range metrics {
+		count++
+	}
+	return count
+}
+
+// registerExporterCollector registers a collector of exporter metrics, replacing a previous registration.
+func registerExporterCollector(collector prometheus.Collector) error {
+	prometheus.Unregister(collector)
+
+	err := prometheus.Register(collector)
+	if err != nil {
+		logger.Error("failed to register exporter metrics", zap.Error(err))
+		return err
+	}
+	return nil
+}
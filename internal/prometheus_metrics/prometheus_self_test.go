@@ -0,0 +1,39 @@
+package prometheusmetrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCountSeries(t *testing.T) {
+	// Arrange
+	gaugeVec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "gpu_fan_speed_count_test"}, []string{"gpu_id", "fan"})
+	gaugeVec.WithLabelValues("0", "0").Set(30)
+	gaugeVec.WithLabelValues("0", "1").Set(40)
+
+	// Act
+	count := countSeries(gaugeVec)
+
+	// Assert
+	if count != 2 {
+		t.Errorf("Expected 2 series, got %v", count)
+	}
+}
+
+func TestRecordConfigLoad(t *testing.T) {
+	recordConfigLoad(errors.New("invalid config"))
+	if value := testutil.ToFloat64(configLastLoadSuccess); value != 0 {
+		t.Errorf("Expected 0 after a failed load, got %v", value)
+	}
+
+	recordConfigLoad(nil)
+	if value := testutil.ToFloat64(configLastLoadSuccess); value != 1 {
+		t.Errorf("Expected 1 after a successful load, got %v", value)
+	}
+	if value := testutil.ToFloat64(configLastLoadSuccessTimestamp); value == 0 {
+		t.Error("Expected the timestamp of the successful load")
+	}
+}
@@ -0,0 +1,201 @@
+// Package pushgateway pushes the collected GPU metrics to a Prometheus Pushgateway,
+// for runs too short to be scraped such as CI benchmark jobs.
+package pushgateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	nvidiaMetrics "github.com/rupeshtr78/nvidia-metrics/internal/nvidia-metrics"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/pushurl"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Defaults of the options
+const (
+	DefaultJob      = "nvidia-metrics"
+	DefaultInterval = 30 * time.Second
+)
+
+// requestTimeout bounds every push and the delete on shutdown
+const requestTimeout = 10 * time.Second
+
+// collectedPollInterval is how often a one-shot push checks whether the first collection finished
+const collectedPollInterval = 100 * time.Millisecond
+
+// Options configures the Pushgateway pusher.
+type Options struct {
+	// URL of the Pushgateway, basic auth credentials can be given as user:password@
+	URL string
+	// Job is the job grouping label, DefaultJob if empty
+	Job string
+	// Grouping labels next to the job, e.g. instance and a CI job id
+	Grouping map[string]string
+	// Interval between pushes, DefaultInterval if zero
+	Interval time.Duration
+	// Once pushes the metrics of the first collection and stops the exporter, the group is kept
+	Once bool
+	// DeleteOnShutdown deletes the group when the exporter stops, so finished runs don't linger as stale series
+	DeleteOnShutdown bool
+	// Gatherer the metrics are read from, the default Prometheus registry if nil
+	Gatherer prometheus.Gatherer
+}
+
+// Pusher pushes the metrics of the Prometheus registry to a Pushgateway group.
+type Pusher struct {
+	options Options
+	pusher  *push.Pusher
+	// collected reports whether a collection succeeded, or fails when the first collection did
+	collected func() (bool, error)
+}
+
+// NewPusher validates the options and creates a pusher.
+func NewPusher(options Options) (*Pusher, error) {
+	if err := pushurl.Validate("Pushgateway URL", options.URL); err != nil {
+		return nil, err
+	}
+
+	if options.Job == "" {
+		options.Job = DefaultJob
+	}
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.Gatherer == nil {
+		options.Gatherer = prometheus.DefaultGatherer
+	}
+
+	pusher := push.New(options.URL, options.Job).
+		Gatherer(options.Gatherer).
+		Client(&http.Client{Timeout: requestTimeout})
+
+	for name, value := range options.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	if err := pusher.Error(); err != nil {
+		return nil, fmt.Errorf("invalid Pushgateway grouping: %w", err)
+	}
+
+	return &Pusher{
+		options:   options,
+		pusher:    pusher,
+		collected: firstCollection,
+	}, nil
+}
+
+// firstCollection reports whether a collection succeeded. A finished collection that failed, e.g. as no GPU
+// was found, is an error, so a one-shot push doesn't push empty metrics or wait forever.
+func firstCollection() (bool, error) {
+	status := nvidiaMetrics.Status()
+	if !status.LastSuccess.IsZero() {
+		return true, nil
+	}
+	if !status.LastCollection.IsZero() {
+		return false, fmt.Errorf("the first collection failed: %s", status.LastError)
+	}
+	return false, nil
+}
+
+// Run pushes the metrics every interval until the context is cancelled, or once the first collection finished
+// for a one-shot push. On a clean shutdown the metrics are pushed a last time or the group is deleted.
+func (p *Pusher) Run(ctx context.Context) error {
+	logger.Info("Starting Pushgateway push",
+		zap.String("url", pushurl.Redact(p.options.URL)),
+		zap.String("job", p.options.Job),
+		zap.Any("grouping", p.options.Grouping),
+		zap.Bool("once", p.options.Once),
+		zap.Duration("interval", p.options.Interval),
+	)
+
+	if p.options.Once {
+		return p.pushOnce(ctx)
+	}
+
+	ticker := time.NewTicker(p.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return p.shutdown()
+		case <-ticker.C:
+			err := p.push(ctx)
+			if err != nil {
+				// the Pushgateway may be restarting, push again on the next interval
+				logger.Error("Failed to push metrics to the Pushgateway", zap.Error(err))
+			}
+		}
+	}
+}
+
+// pushOnce waits for the first collection and pushes its metrics, it fails when the collection did.
+func (p *Pusher) pushOnce(ctx context.Context) error {
+	ticker := time.NewTicker(collectedPollInterval)
+	defer ticker.Stop()
+
+	for {
+		collected, err := p.collected()
+		if err != nil {
+			return fmt.Errorf("nothing was pushed to the Pushgateway: %w", err)
+		}
+		if collected {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped before the first collection, nothing was pushed to the Pushgateway")
+		case <-ticker.C:
+		}
+	}
+
+	err := p.push(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics to the Pushgateway: %w", err)
+	}
+
+	logger.Info("Pushed metrics to the Pushgateway once, stopping")
+	return nil
+}
+
+// shutdown deletes the group or pushes the last metrics.
+func (p *Pusher) shutdown() error {
+	if p.options.DeleteOnShutdown {
+		err := p.pusher.Delete()
+		if err != nil {
+			return fmt.Errorf("failed to delete the Pushgateway group: %w", err)
+		}
+		logger.Info("Deleted the Pushgateway group")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	err := p.push(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics to the Pushgateway: %w", err)
+	}
+	return nil
+}
+
+// push replaces the metrics of the group, so series of removed devices don't linger.
+func (p *Pusher) push(ctx context.Context) error {
+	return p.pusher.PushContext(ctx)
+}
+
+// ParseGrouping parses grouping labels given as name=value pairs separated by commas.
+func ParseGrouping(grouping string) (map[string]string, error) {
+	labels, err := utils.ParsePairs(grouping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grouping label: %w", err)
+	}
+	return labels, nil
+}
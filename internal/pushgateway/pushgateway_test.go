@@ -0,0 +1,200 @@
+package pushgateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+)
+
+var _ = logger.GetLogger("debug", false, "")
+
+// testGateway is a Pushgateway recording the received requests and their bodies.
+type testGateway struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+}
+
+func newTestGateway(t *testing.T) (*testGateway, *httptest.Server) {
+	t.Helper()
+	gateway := &testGateway{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gateway.mu.Lock()
+		gateway.requests = append(gateway.requests, r.Method+" "+r.URL.Path)
+		gateway.bodies = append(gateway.bodies, string(body))
+		gateway.mu.Unlock()
+
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return gateway, server
+}
+
+func (g *testGateway) received() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.requests...)
+}
+
+func (g *testGateway) receivedBodies() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.bodies...)
+}
+
+// newTestPusher creates a pusher pushing a single gauge, as if the first collection already finished.
+func newTestPusher(t *testing.T, options Options) *Pusher {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	temperature := prometheus.NewGauge(prometheus.GaugeOpts{Name: "gpu_temperature", Help: "GPU temperature"})
+	registry.MustRegister(temperature)
+	temperature.Set(42)
+
+	options.Gatherer = registry
+	options.Grouping = map[string]string{"instance": "gpu-box", "ci_job_id": "1234"}
+
+	pusher, err := NewPusher(options)
+	if err != nil {
+		t.Fatalf("Failed to create pusher: %v", err)
+	}
+	pusher.collected = func() (bool, error) { return true, nil }
+	return pusher
+}
+
+// isGroupRequest reports whether the request is a method request to the test group, the grouping labels come in any order.
+func isGroupRequest(request string, method string) bool {
+	for _, path := range []string{
+		"/metrics/job/nvidia-metrics/ci_job_id/1234/instance/gpu-box",
+		"/metrics/job/nvidia-metrics/instance/gpu-box/ci_job_id/1234",
+	} {
+		if request == method+" "+path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPusherOnce(t *testing.T) {
+	// Arrange
+	gateway, server := newTestGateway(t)
+	pusher := newTestPusher(t, Options{URL: server.URL, Once: true, DeleteOnShutdown: true})
+
+	// Act
+	err := pusher.Run(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected the one-shot push to succeed, got %v", err)
+	}
+	if got := gateway.received(); len(got) != 1 || !isGroupRequest(got[0], http.MethodPut) {
+		t.Errorf("Expected a single push to the group, got %v", got)
+	}
+	if bodies := gateway.receivedBodies(); !strings.Contains(bodies[0], "gpu_temperature") {
+		t.Errorf("Expected the metrics to be pushed, got %q", bodies[0])
+	}
+}
+
+func TestPusherOnceWaitsForCollection(t *testing.T) {
+	// Arrange
+	_, server := newTestGateway(t)
+	pusher := newTestPusher(t, Options{URL: server.URL, Once: true})
+	pusher.collected = func() (bool, error) { return false, nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*collectedPollInterval)
+	defer cancel()
+
+	// Act
+	err := pusher.Run(ctx)
+
+	// Assert
+	if err == nil {
+		t.Error("Expected an error when stopped before the first collection")
+	}
+}
+
+func TestPusherOnceFailsWithFailedCollection(t *testing.T) {
+	// Arrange
+	gateway, server := newTestGateway(t)
+	pusher := newTestPusher(t, Options{URL: server.URL, Once: true})
+	pusher.collected = func() (bool, error) { return false, errors.New("the first collection failed: no GPU devices found") }
+
+	// Act
+	err := pusher.Run(context.Background())
+
+	// Assert
+	if err == nil {
+		t.Error("Expected an error when the first collection failed")
+	}
+	if got := gateway.received(); len(got) != 0 {
+		t.Errorf("Expected nothing to be pushed, got %v", got)
+	}
+}
+
+func TestPusherDeletesGroupOnShutdown(t *testing.T) {
+	// Arrange
+	gateway, server := newTestGateway(t)
+	pusher := newTestPusher(t, Options{URL: server.URL, Interval: 10 * time.Millisecond, DeleteOnShutdown: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	// Act
+	go func() {
+		runErr <- pusher.Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	// Assert
+	if err := <-runErr; err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+
+	requests := gateway.received()
+	if len(requests) < 2 || !isGroupRequest(requests[0], http.MethodPut) {
+		t.Fatalf("Expected periodic pushes to the group, got %v", requests)
+	}
+	if last := requests[len(requests)-1]; !isGroupRequest(last, http.MethodDelete) {
+		t.Errorf("Expected the group to be deleted last, got %v", last)
+	}
+}
+
+func TestParseGrouping(t *testing.T) {
+	tests := []struct {
+		grouping string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{"", map[string]string{}, false},
+		{"ci_job_id=1234, benchmark = resnet50", map[string]string{"ci_job_id": "1234", "benchmark": "resnet50"}, false},
+		{"ci_job_id", nil, true},
+		{"=1234", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseGrouping(tt.grouping)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.grouping, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%q: expected %v, got %v", tt.grouping, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Label is a label of a time series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// TimeSeries is a series with a single sample, the unit of a remote-write request.
+type TimeSeries struct {
+	Labels    []Label // sorted by name, __name__ included
+	Value     float64
+	Timestamp int64 // milliseconds since epoch
+}
+
+// Field numbers of the remote-write 1.0 protobuf messages, see prometheus/prompb/types.proto
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// encodeWriteRequest encodes the series as a snappy-compressed prometheus.WriteRequest.
+func encodeWriteRequest(series []TimeSeries) []byte {
+	var request []byte
+	for _, ts := range series {
+		request = protowire.AppendTag(request, writeRequestTimeseries, protowire.BytesType)
+		request = protowire.AppendBytes(request, encodeTimeSeries(ts))
+	}
+	return snappy.Encode(nil, request)
+}
+
+func encodeTimeSeries(ts TimeSeries) []byte {
+	var b []byte
+	for _, label := range ts.Labels {
+		var l []byte
+		l = protowire.AppendTag(l, labelName, protowire.BytesType)
+		l = protowire.AppendString(l, label.Name)
+		l = protowire.AppendTag(l, labelValue, protowire.BytesType)
+		l = protowire.AppendString(l, label.Value)
+
+		b = protowire.AppendTag(b, timeSeriesLabels, protowire.BytesType)
+		b = protowire.AppendBytes(b, l)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(ts.Value))
+	sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(ts.Timestamp))
+
+	b = protowire.AppendTag(b, timeSeriesSamples, protowire.BytesType)
+	b = protowire.AppendBytes(b, sample)
+	return b
+}
+
+// toTimeSeries converts the gathered metric families into series, histograms and summaries are
+// split into their _bucket, _sum and _count series like on the /metrics endpoint.
+func toTimeSeries(families []*dto.MetricFamily, externalLabels map[string]string, timestamp int64) []TimeSeries {
+	var series []TimeSeries
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			ts := timestamp
+			if metric.TimestampMs != nil {
+				ts = metric.GetTimestampMs()
+			}
+
+			add := func(name string, value float64, extra ...Label) {
+				series = append(series, TimeSeries{
+					Labels:    seriesLabels(name, metric.GetLabel(), externalLabels, extra...),
+					Value:     value,
+					Timestamp: ts,
+				})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, metric.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				for _, bucket := range histogram.GetBucket() {
+					// the +Inf bucket is added from the sample count
+					if math.IsInf(bucket.GetUpperBound(), 1) {
+						continue
+					}
+					add(name+"_bucket", float64(bucket.GetCumulativeCount()), Label{"le", formatFloat(bucket.GetUpperBound())})
+				}
+				add(name+"_bucket", float64(histogram.GetSampleCount()), Label{"le", "+Inf"})
+				add(name+"_sum", histogram.GetSampleSum())
+				add(name+"_count", float64(histogram.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, quantile := range summary.GetQuantile() {
+					add(name, quantile.GetValue(), Label{"quantile", formatFloat(quantile.GetQuantile())})
+				}
+				add(name+"_sum", summary.GetSampleSum())
+				add(name+"_count", float64(summary.GetSampleCount()))
+			}
+		}
+	}
+	return series
+}
+
+// seriesLabels returns the sorted labels of a series, the labels of the metric win over the external labels.
+func seriesLabels(name string, pairs []*dto.LabelPair, externalLabels map[string]string, extra ...Label) []Label {
+	values := make(map[string]string, len(pairs)+len(externalLabels)+len(extra)+1)
+	for name, value := range externalLabels {
+		values[name] = value
+	}
+	for _, pair := range pairs {
+		values[pair.GetName()] = pair.GetValue()
+	}
+	for _, label := range extra {
+		values[label.Name] = label.Value
+	}
+	values["__name__"] = name
+
+	labels := make([]Label, 0, len(values))
+	for name, value := range values {
+		// empty labels are the same as missing ones
+		if value == "" {
+			continue
+		}
+		labels = append(labels, Label{Name: name, Value: value})
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,166 @@
+package remotewrite
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes a snappy-compressed prometheus.WriteRequest, the samples of a series are flattened.
+func decodeWriteRequest(data []byte) ([]TimeSeries, error) {
+	request, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress write request: %w", err)
+	}
+
+	var series []TimeSeries
+	err = decodeFields(request, func(num protowire.Number, value []byte) error {
+		if num != writeRequestTimeseries {
+			return nil
+		}
+		decoded, err := decodeTimeSeries(value)
+		series = append(series, decoded...)
+		return err
+	})
+	return series, err
+}
+
+func decodeTimeSeries(data []byte) ([]TimeSeries, error) {
+	var labels []Label
+	var samples []TimeSeries
+
+	err := decodeFields(data, func(num protowire.Number, value []byte) error {
+		switch num {
+		case timeSeriesLabels:
+			var label Label
+			err := decodeFields(value, func(num protowire.Number, value []byte) error {
+				switch num {
+				case labelName:
+					label.Name = string(value)
+				case labelValue:
+					label.Value = string(value)
+				}
+				return nil
+			})
+			labels = append(labels, label)
+			return err
+		case timeSeriesSamples:
+			var sample TimeSeries
+			err := decodeFields(value, func(num protowire.Number, value []byte) error {
+				switch num {
+				case sampleValue:
+					v, _ := protowire.ConsumeFixed64(value)
+					sample.Value = math.Float64frombits(v)
+				case sampleTimestamp:
+					v, _ := protowire.ConsumeVarint(value)
+					sample.Timestamp = int64(v)
+				}
+				return nil
+			})
+			samples = append(samples, sample)
+			return err
+		}
+		return nil
+	})
+
+	for i := range samples {
+		samples[i].Labels = labels
+	}
+	return samples, err
+}
+
+// decodeFields calls fn with the number and the raw value of every field of the message.
+// Length-delimited values are passed without their length, fixed and varint values as encoded.
+func decodeFields(data []byte, fn func(num protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = v, data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = data[:n], data[n:]
+		}
+
+		if err := fn(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	// Arrange
+	want := []TimeSeries{
+		{Labels: []Label{{"__name__", "gpu_temperature"}, {"gpu_id", "0"}}, Value: 42, Timestamp: 1700000000000},
+		{Labels: []Label{{"__name__", "gpu_power_usage"}, {"gpu_id", "1"}}, Value: 250.5, Timestamp: 1700000000000},
+	}
+
+	// Act
+	got, err := decodeWriteRequest(encodeWriteRequest(want))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Failed to decode write request: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestToTimeSeries(t *testing.T) {
+	// Arrange
+	registry := prometheus.NewRegistry()
+	temperature := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "gpu_temperature", Help: "GPU temperature"}, []string{"gpu_id", "gpu_name"})
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "collector_duration_seconds", Help: "Duration", Buckets: []float64{0.1, 1}})
+	registry.MustRegister(temperature, duration)
+	temperature.WithLabelValues("0", "").Set(42)
+	duration.Observe(0.5)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather: %v", err)
+	}
+
+	// Act
+	series := toTimeSeries(families, map[string]string{"job": "nvidia-metrics", "instance": "gpu-box"}, 1000)
+
+	// Assert
+	got := make(map[string]float64)
+	for _, ts := range series {
+		if ts.Timestamp != 1000 {
+			t.Errorf("Expected timestamp 1000, got %d", ts.Timestamp)
+		}
+		got[fmt.Sprint(ts.Labels)] = ts.Value
+	}
+
+	want := map[string]float64{
+		"[{__name__ collector_duration_seconds_bucket} {instance gpu-box} {job nvidia-metrics} {le 0.1}]":  0,
+		"[{__name__ collector_duration_seconds_bucket} {instance gpu-box} {job nvidia-metrics} {le 1}]":    1,
+		"[{__name__ collector_duration_seconds_bucket} {instance gpu-box} {job nvidia-metrics} {le +Inf}]": 1,
+		"[{__name__ collector_duration_seconds_sum} {instance gpu-box} {job nvidia-metrics}]":              0.5,
+		"[{__name__ collector_duration_seconds_count} {instance gpu-box} {job nvidia-metrics}]":            1,
+		"[{__name__ gpu_temperature} {gpu_id 0} {instance gpu-box} {job nvidia-metrics}]":                  42,
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
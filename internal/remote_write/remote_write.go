@@ -0,0 +1,287 @@
+// Package remotewrite pushes the collected GPU metrics to a Prometheus remote-write endpoint,
+// for exporters that can't be scraped, e.g. behind NAT.
+// Every interval the registry is gathered, written to the WAL in batches and the WAL is sent oldest first.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/logger"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/pushurl"
+	"github.com/rupeshtr78/nvidia-metrics/pkg/version"
+	"go.uber.org/zap"
+)
+
+// Defaults of the options
+const (
+	DefaultInterval   = 30 * time.Second
+	DefaultBatchSize  = 2000
+	DefaultWALMaxSize = 256 << 20
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+)
+
+// shutdownTimeout bounds the final push on shutdown, what isn't sent stays in the WAL
+const shutdownTimeout = 10 * time.Second
+
+var (
+	batchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gpu_metrics_remote_write_batches_total",
+		Help: "The total number of remote-write batches by result, sent, rejected by the endpoint or dropped from a full WAL.",
+	}, []string{"result"})
+
+	pendingBatches = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gpu_metrics_remote_write_pending_batches",
+		Help: "The number of remote-write batches in the WAL waiting to be sent.",
+	})
+)
+
+// Options configures the remote-write client.
+type Options struct {
+	// URL of the remote-write endpoint, basic auth credentials can be given as user:password@
+	URL string
+	// Interval between pushes, DefaultInterval if zero
+	Interval time.Duration
+	// BatchSize is the maximum number of samples per request, DefaultBatchSize if zero
+	BatchSize int
+	// WALDir is the directory the batches are kept in until they are sent
+	WALDir string
+	// WALMaxSize is the size in bytes the WAL is limited to by dropping the oldest batches, DefaultWALMaxSize if zero
+	WALMaxSize int64
+	// Timeout of a request, DefaultTimeout if zero
+	Timeout time.Duration
+	// MaxRetries of a batch within a push before it is left in the WAL for the next push, DefaultMaxRetries if zero
+	MaxRetries int
+	// ExternalLabels are added to every series, e.g. job and instance which a scrape would add
+	ExternalLabels map[string]string
+	// Gatherer the metrics are read from, the default Prometheus registry if nil
+	Gatherer prometheus.Gatherer
+}
+
+// Client pushes the metrics of the Prometheus registry to a remote-write endpoint on an interval.
+type Client struct {
+	options    Options
+	httpClient *http.Client
+	wal        *WAL
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// recoverableError is a failed request that may succeed when retried, a network error, 5xx or 429.
+type recoverableError struct {
+	error
+}
+
+// NewClient validates the options and creates a client.
+func NewClient(options Options) (*Client, error) {
+	if err := pushurl.Validate("remote-write URL", options.URL); err != nil {
+		return nil, err
+	}
+
+	if options.WALDir == "" {
+		return nil, fmt.Errorf("remote-write WAL directory is required, e.g. /var/lib/nvidia-metrics/remote-write")
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.WALMaxSize <= 0 {
+		options.WALMaxSize = DefaultWALMaxSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = DefaultMaxRetries
+	}
+	if options.Gatherer == nil {
+		options.Gatherer = prometheus.DefaultGatherer
+	}
+
+	return &Client{
+		options:    options,
+		httpClient: &http.Client{Timeout: options.Timeout},
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}, nil
+}
+
+// Run pushes the metrics every interval until the context is cancelled, then pushes them a last time.
+// Batches left over from the last run are sent first.
+func (c *Client) Run(ctx context.Context) error {
+	wal, err := OpenWAL(c.options.WALDir, c.options.WALMaxSize)
+	if err != nil {
+		return err
+	}
+	c.wal = wal
+	pendingBatches.Set(float64(wal.Len()))
+
+	logger.Info("Starting remote-write",
+		zap.String("url", pushurl.Redact(c.options.URL)),
+		zap.Duration("interval", c.options.Interval),
+		zap.String("wal_dir", c.options.WALDir),
+		zap.Int("pending_batches", wal.Len()),
+	)
+
+	ticker := time.NewTicker(c.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping remote-write")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			c.push(shutdownCtx)
+			if pending := wal.Len(); pending > 0 {
+				logger.Warn("Remote-write batches left in the WAL for the next run", zap.Int("pending_batches", pending))
+			}
+			return nil
+		case <-ticker.C:
+			c.push(ctx)
+		}
+	}
+}
+
+// push writes the current samples to the WAL and sends the WAL.
+func (c *Client) push(ctx context.Context) {
+	err := c.collect(time.Now())
+	if err != nil {
+		logger.Error("Failed to write samples to the remote-write WAL", zap.Error(err))
+	}
+
+	c.flush(ctx)
+	pendingBatches.Set(float64(c.wal.Len()))
+}
+
+// collect gathers the registry and appends the samples to the WAL in batches of at most BatchSize samples.
+func (c *Client) collect(now time.Time) error {
+	families, err := c.options.Gatherer.Gather()
+	if err != nil {
+		// the registry returns what it could gather next to the error
+		logger.Warn("Failed to gather some metrics for remote-write", zap.Error(err))
+	}
+
+	series := toTimeSeries(families, c.options.ExternalLabels, now.UnixMilli())
+	for start := 0; start < len(series); start += c.options.BatchSize {
+		end := min(start+c.options.BatchSize, len(series))
+
+		dropped, err := c.wal.Append(encodeWriteRequest(series[start:end]))
+		if dropped > 0 {
+			logger.Warn("Remote-write WAL is full, dropped the oldest batches", zap.Int("dropped", dropped))
+			batchesTotal.WithLabelValues("dropped").Add(float64(dropped))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flush sends the batches of the WAL oldest first, so the samples of a series arrive in order.
+// It stops at the first batch that still fails after the retries, it is sent again on the next push.
+func (c *Client) flush(ctx context.Context) {
+	for {
+		seq, data, ok, err := c.wal.Oldest()
+		if !ok {
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to read remote-write batch, dropping it", zap.Uint64("batch", seq), zap.Error(err))
+			_ = c.wal.Remove(seq)
+			batchesTotal.WithLabelValues("dropped").Inc()
+			continue
+		}
+
+		err = c.sendWithRetry(ctx, data)
+		var recoverable recoverableError
+		if errors.As(err, &recoverable) {
+			logger.Warn("Remote-write endpoint unavailable, keeping batches in the WAL",
+				zap.Int("pending_batches", c.wal.Len()),
+				zap.Error(err),
+			)
+			return
+		}
+
+		if err != nil {
+			// the endpoint will reject the batch again, e.g. out of order samples
+			logger.Error("Remote-write batch rejected, dropping it", zap.Uint64("batch", seq), zap.Error(err))
+			batchesTotal.WithLabelValues("rejected").Inc()
+		} else {
+			batchesTotal.WithLabelValues("sent").Inc()
+		}
+
+		err = c.wal.Remove(seq)
+		if err != nil {
+			logger.Error("Failed to remove sent remote-write batch", zap.Uint64("batch", seq), zap.Error(err))
+			return
+		}
+	}
+}
+
+// sendWithRetry sends the batch, retrying recoverable errors with an exponential backoff.
+func (c *Client) sendWithRetry(ctx context.Context, data []byte) error {
+	backoff := c.minBackoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, data)
+
+		var recoverable recoverableError
+		if err == nil || !errors.As(err, &recoverable) || attempt >= c.options.MaxRetries {
+			return err
+		}
+
+		logger.Debug("Retrying remote-write batch", zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, c.maxBackoff)
+	}
+}
+
+// send posts a snappy-compressed write request.
+func (c *Client) send(ctx context.Context, data []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.options.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	request.Header.Set("User-Agent", "nvidia-metrics/"+version.Version)
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("remote-write returned %s: %s", response.Status, bytes.TrimSpace(body))
+	if response.StatusCode/100 == 5 || response.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
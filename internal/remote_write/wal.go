@@ -0,0 +1,199 @@
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	walExtension = ".wal"
+	tmpExtension = ".tmp"
+)
+
+// WAL is an on-disk queue of encoded write requests, so samples collected during an outage are sent
+// once the endpoint is reachable again, also across restarts.
+// Every batch is a file named by its sequence number, written to a temporary file and renamed once complete.
+type WAL struct {
+	mu       sync.Mutex
+	dir      string
+	maxSize  int64
+	segments []segment // oldest first
+	size     int64
+	next     uint64
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// OpenWAL opens the WAL in the directory, creating it if needed, and loads the batches left by the last run.
+// Once the WAL holds more than maxSize bytes the oldest batches are dropped, zero doesn't limit the size.
+func OpenWAL(dir string, maxSize int64) (*WAL, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL directory: %w", err)
+	}
+
+	w := &WAL{dir: dir, maxSize: maxSize}
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// a batch that was being written when the exporter stopped
+		if strings.HasSuffix(name, tmpExtension) {
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+
+		if !strings.HasSuffix(name, walExtension) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAL segment %s: %w", name, err)
+		}
+
+		w.segments = append(w.segments, segment{seq: seq, size: info.Size()})
+		w.size += info.Size()
+		if seq >= w.next {
+			w.next = seq + 1
+		}
+	}
+
+	sort.Slice(w.segments, func(i, j int) bool {
+		return w.segments[i].seq < w.segments[j].seq
+	})
+
+	return w, nil
+}
+
+// Append adds a batch to the WAL, it returns the number of old batches dropped to stay within the size limit.
+func (w *WAL) Append(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seq := w.next
+	path := w.path(seq)
+	tmp := path + tmpExtension
+
+	err := writeFileSync(tmp, data)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("failed to write WAL segment: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("failed to commit WAL segment: %w", err)
+	}
+
+	w.next++
+	w.segments = append(w.segments, segment{seq: seq, size: int64(len(data))})
+	w.size += int64(len(data))
+
+	dropped := 0
+	// keep the newest batch even if it is larger than the limit on its own
+	for w.maxSize > 0 && w.size > w.maxSize && len(w.segments) > 1 {
+		err = w.remove(w.segments[0].seq)
+		if err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+
+	return dropped, nil
+}
+
+// Oldest returns the oldest batch and its sequence number, false if the WAL is empty.
+func (w *WAL) Oldest() (uint64, []byte, bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.segments) == 0 {
+		return 0, nil, false, nil
+	}
+
+	seq := w.segments[0].seq
+	data, err := os.ReadFile(w.path(seq))
+	if err != nil {
+		return seq, nil, true, fmt.Errorf("failed to read WAL segment: %w", err)
+	}
+	return seq, data, true, nil
+}
+
+// Remove deletes the batch once it was sent.
+func (w *WAL) Remove(seq uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.remove(seq)
+}
+
+func (w *WAL) remove(seq uint64) error {
+	for i, s := range w.segments {
+		if s.seq != seq {
+			continue
+		}
+
+		err := os.Remove(w.path(seq))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove WAL segment: %w", err)
+		}
+
+		w.segments = append(w.segments[:i], w.segments[i+1:]...)
+		w.size -= s.size
+		return nil
+	}
+	return nil
+}
+
+// Len returns the number of batches in the WAL.
+func (w *WAL) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.segments)
+}
+
+// Size returns the size of the batches in the WAL in bytes.
+func (w *WAL) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+func (w *WAL) path(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, walExtension))
+}
+
+// writeFileSync writes the file and syncs it to disk, so a batch survives a power loss once renamed.
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
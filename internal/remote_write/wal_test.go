@@ -0,0 +1,91 @@
+package remotewrite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAL_AppendOldestRemove(t *testing.T) {
+	// Arrange
+	wal, err := OpenWAL(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+
+	// Act
+	for _, batch := range []string{"first", "second"} {
+		if _, err := wal.Append([]byte(batch)); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+
+	// Assert
+	seq, data, ok, err := wal.Oldest()
+	if err != nil || !ok || string(data) != "first" {
+		t.Fatalf("Expected the first batch, got %q %v %v", data, ok, err)
+	}
+
+	if err := wal.Remove(seq); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+
+	_, data, _, _ = wal.Oldest()
+	if string(data) != "second" || wal.Len() != 1 || wal.Size() != int64(len("second")) {
+		t.Errorf("Expected only the second batch left, got %q, %d batches, %d bytes", data, wal.Len(), wal.Size())
+	}
+}
+
+func TestWAL_Reopen(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	wal, _ := OpenWAL(dir, 0)
+	_, _ = wal.Append([]byte("pending"))
+
+	// a batch that was being written when the exporter stopped
+	err := os.WriteFile(filepath.Join(dir, "00000000000000000001.wal.tmp"), []byte("partial"), 0o640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	reopened, err := OpenWAL(dir, 0)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	if reopened.Len() != 1 {
+		t.Fatalf("Expected the pending batch to be loaded, got %d batches", reopened.Len())
+	}
+
+	_, _ = reopened.Append([]byte("next"))
+	_, data, _, _ := reopened.Oldest()
+	if string(data) != "pending" {
+		t.Errorf("Expected the batch of the last run first, got %q", data)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000001.wal.tmp")); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial batch to be removed, got %v", err)
+	}
+}
+
+func TestWAL_MaxSize(t *testing.T) {
+	// Arrange
+	wal, _ := OpenWAL(t.TempDir(), 10)
+
+	// Act
+	_, _ = wal.Append([]byte("aaaa"))
+	_, _ = wal.Append([]byte("bbbb"))
+	dropped, err := wal.Append([]byte("cccc"))
+
+	// Assert
+	if err != nil || dropped != 1 {
+		t.Fatalf("Expected the oldest batch to be dropped, got %d %v", dropped, err)
+	}
+
+	_, data, _, _ := wal.Oldest()
+	if string(data) != "bbbb" || wal.Len() != 2 {
+		t.Errorf("Expected the two newest batches, got %q and %d batches", data, wal.Len())
+	}
+}
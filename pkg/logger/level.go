@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// atomicLevel is the level of the global logger, it is retained so the level can be changed at runtime.
+var atomicLevel = zap.NewAtomicLevel()
+
+// LevelHandler serves the log level. GET returns the level as {"level":"info"},
+// PUT with the same body changes it without a restart.
+func LevelHandler() http.Handler {
+	return atomicLevel
+}
+
+// GetLevel returns the current log level.
+func GetLevel() zapcore.Level {
+	return atomicLevel.Level()
+}
+
+// IncreaseVerbosity lowers the log level by one step e.g. info to debug, down to debug.
+func IncreaseVerbosity() zapcore.Level {
+	level := atomicLevel.Level()
+	if level > zapcore.DebugLevel {
+		level--
+		atomicLevel.SetLevel(level)
+	}
+	return level
+}
+
+// DecreaseVerbosity raises the log level by one step e.g. info to warn, up to error.
+func DecreaseVerbosity() zapcore.Level {
+	level := atomicLevel.Level()
+	if level < zapcore.ErrorLevel {
+		level++
+		atomicLevel.SetLevel(level)
+	}
+	return level
+}
+
+// WatchLevelSignals changes the log level on SIGUSR1 (more verbose) and SIGUSR2 (less verbose)
+// until the context is done.
+func WatchLevelSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				var level zapcore.Level
+				if sig == syscall.SIGUSR1 {
+					level = IncreaseVerbosity()
+				} else {
+					level = DecreaseVerbosity()
+				}
+				Info("Changed log level", zap.String("signal", sig.String()), zap.String("level", level.String()))
+			}
+		}
+	}()
+}
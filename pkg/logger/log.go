@@ -1,5 +1,5 @@
 // Description: This file contains the log functions for the package.
-// Using uber-go/zap for logging.
+// Using uber-go/zap for logging, with lumberjack for file rotation.
 // In this package we initate the logger.
 // go get -u go.uber.org/zap
 // go get -u go.uber.org/zap/zapcore
@@ -7,10 +7,15 @@ package logger
 
 import (
 	"fmt"
+	"log/slog"
+	"log/syslog"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/exp/zapslog"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // logger is the global logger instance.
@@ -26,71 +31,155 @@ func Loggerinit(level string, fileLog bool, filePath string) {
 
 }
 
-func GetLogger(level string, fileLog bool, filePath string) (err error) {
-	config := zap.NewProductionConfig()
-	config.EncoderConfig.TimeKey = "timestamp"
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	config.InitialFields = map[string]interface{}{
-		"app": "nvidia-metrics",
-	}
-	config.Level = setLogLevel(level)
-
-	// Enable caller information reporting.
-	// Modify the EncoderConfig for the caller key and format
-	config.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder // Use ShortCallerEncoder to log the relative path
-	config.EncoderConfig.CallerKey = "caller"
-
-	if fileLog {
-		config.OutputPaths = []string{"stdout", filePath}
-		config.ErrorOutputPaths = []string{"stderr", filePath}
-	} else {
-		config.OutputPaths = []string{"stdout"}
-		config.ErrorOutputPaths = []string{"stderr"}
+// Options configures the sinks, encoding, rotation and sampling of the logger.
+type Options struct {
+	Level    string
+	Encoding string // json or console
+
+	// FileLog writes the logs to FilePath, rotated by size and age
+	FileLog    bool
+	FilePath   string
+	MaxSizeMB  int  // size in megabytes before the file is rotated
+	MaxAgeDays int  // days to retain rotated files, 0 keeps them
+	MaxBackups int  // number of rotated files to retain, 0 keeps them
+	Compress   bool // gzip rotated files
+
+	// Syslog writes the logs to the local syslog daemon, journald reads them from there as well
+	Syslog    bool
+	SyslogTag string
+
+	// Sampling drops repeated entries at SamplingLevel and above with the same level and message, logging the
+	// first SamplingInitial entries per SamplingWindow and every SamplingThereafter entry after that, 0 drops them all.
+	// Entries below SamplingLevel, operational events such as a device set change, are never sampled
+	Sampling           bool
+	SamplingLevel      zapcore.Level
+	SamplingWindow     time.Duration
+	SamplingInitial    int
+	SamplingThereafter int
+}
+
+// DefaultOptions returns the logger options used by GetLogger.
+func DefaultOptions() Options {
+	return Options{
+		Level:              "info",
+		Encoding:           "json",
+		FilePath:           "logs/gpu-metrics.log",
+		MaxSizeMB:          100,
+		MaxAgeDays:         28,
+		MaxBackups:         5,
+		Compress:           true,
+		SyslogTag:          "nvidia-metrics",
+		Sampling:           true,
+		SamplingLevel:      zapcore.WarnLevel,
+		SamplingWindow:     time.Minute,
+		SamplingInitial:    5,
+		SamplingThereafter: 100,
 	}
+}
+
+// GetLogger initializes the logger with the default options.
+func GetLogger(level string, fileLog bool, filePath string) (err error) {
+	options := DefaultOptions()
+	options.Level = level
+	options.FileLog = fileLog
+	options.FilePath = filePath
 
-	logger, err = config.Build(zap.AddCallerSkip(1)) // Skip one level to account for this wrapper.
+	return InitLogger(options)
+}
 
+// InitLogger initializes the global logger from the options.
+func InitLogger(options Options) error {
+	encoder, err := newEncoder(options.Encoding)
 	if err != nil {
-		err = fmt.Errorf("failed to initialize logger: %v", err)
-		return err
+		return fmt.Errorf("failed to initialize logger: %v", err)
 	}
 
-	return nil
+	level := setLogLevel(options.Level)
+	cores := []zapcore.Core{
+		zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), level),
+	}
 
-}
+	if options.FileLog {
+		cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(newRotatingFile(options)), level))
+	}
 
-// // Configure the logger to write to the file
-func configureZapCore(fileName string) zapcore.Core {
-	// Create a file for logging
-	file, err := createLogFile(fileName)
-	if err != nil {
-		fmt.Println("Failed to create log file: ", err)
+	if options.Syslog {
+		writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, options.SyslogTag)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: failed to connect to syslog: %v", err)
+		}
+		cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(writer), level))
 	}
 
-	// Configure the encoder
-	encoderCfg := zap.NewProductionEncoderConfig()
+	core := zapcore.NewTee(cores...)
+	if options.Sampling {
+		sampled := zapcore.NewSamplerWithOptions(core, options.SamplingWindow, options.SamplingInitial, options.SamplingThereafter)
+		core = zapcore.NewTee(
+			&levelRangeCore{Core: core, enabled: func(l zapcore.Level) bool { return l < options.SamplingLevel }},
+			&levelRangeCore{Core: sampled, enabled: func(l zapcore.Level) bool { return l >= options.SamplingLevel }},
+		)
+	}
 
-	// Create a new JSON encoder
-	encoder := zapcore.NewJSONEncoder(encoderCfg)
+	logger = zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1), // Skip one level to account for this wrapper.
+		zap.AddStacktrace(zap.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+		zap.Fields(zap.String("app", "nvidia-metrics")),
+	)
 
-	// Create a new core
-	writeSyncer := zapcore.AddSync(file)
+	return nil
+}
 
-	// Create a new core
-	core := zapcore.NewCore(encoder, writeSyncer, zap.InfoLevel)
+// levelRangeCore passes only the entries with the enabled levels to the core.
+type levelRangeCore struct {
+	zapcore.Core
+	enabled func(zapcore.Level) bool
+}
 
-	return core
+func (c *levelRangeCore) Enabled(level zapcore.Level) bool {
+	return c.enabled(level) && c.Core.Enabled(level)
+}
 
+func (c *levelRangeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelRangeCore{Core: c.Core.With(fields), enabled: c.enabled}
 }
 
-func createLogFile(fileName string) (zapcore.WriteSyncer, error) {
-	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func (c *levelRangeCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
 
-	if err != nil {
-		return nil, err
+// newEncoder creates the json or console encoder with ISO8601 timestamps and the relative caller path.
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	config := zap.NewProductionEncoderConfig()
+	config.TimeKey = "timestamp"
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.EncodeCaller = zapcore.ShortCallerEncoder
+	config.CallerKey = "caller"
+
+	switch encoding {
+	case "", "json":
+		return zapcore.NewJSONEncoder(config), nil
+	case "console":
+		config.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(config), nil
+	default:
+		return nil, fmt.Errorf("unsupported log encoding: %s", encoding)
 	}
+}
 
-	return zapcore.AddSync(file), nil
+// newRotatingFile creates the log file writer rotating by size and pruning rotated files by age and count.
+func newRotatingFile(options Options) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   options.FilePath,
+		MaxSize:    options.MaxSizeMB,
+		MaxAge:     options.MaxAgeDays,
+		MaxBackups: options.MaxBackups,
+		Compress:   options.Compress,
+	}
 }
 
 func Info(message string, fields ...zap.Field) {
@@ -113,8 +202,25 @@ func Fatal(message string, fields ...zap.Field) {
 	logger.Fatal(message, fields...)
 }
 
+// SlogLogger returns a slog logger writing to the zap logger, for libraries logging with slog.
+func SlogLogger() *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return slog.New(zapslog.NewHandler(logger.Core(), nil))
+}
+
+// Sync flushes any buffered log entries, it has to be called before the process exits.
+func Sync() {
+	if logger == nil {
+		return
+	}
+	// syncing stdout and stderr fails on some platforms, there is nothing left to flush then
+	_ = logger.Sync()
+}
+
+// setLogLevel sets the retained atomic level, so the level can be changed at runtime.
 func setLogLevel(level string) zap.AtomicLevel {
-	atomicLevel := zap.NewAtomicLevel()
 	switch level {
 	case "debug":
 		atomicLevel.SetLevel(zap.DebugLevel)
@@ -1,7 +1,15 @@
 package logger
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -67,3 +75,141 @@ func TestSetLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestVerbosity(t *testing.T) {
+	setLogLevel("info")
+
+	if level := IncreaseVerbosity(); level != zap.DebugLevel {
+		t.Fatalf("Expected %v, got %v", zap.DebugLevel, level)
+	}
+	if level := IncreaseVerbosity(); level != zap.DebugLevel {
+		t.Fatalf("Expected verbosity to stop at %v, got %v", zap.DebugLevel, level)
+	}
+
+	setLogLevel("warn")
+	DecreaseVerbosity()
+	if level := DecreaseVerbosity(); level != zap.ErrorLevel {
+		t.Fatalf("Expected verbosity to stop at %v, got %v", zap.ErrorLevel, level)
+	}
+}
+
+func TestWatchLevelSignals(t *testing.T) {
+	setLogLevel("info")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	WatchLevelSignals(ctx)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for GetLevel() != zap.DebugLevel && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if GetLevel() != zap.DebugLevel {
+		t.Fatalf("Expected %v after SIGUSR1, got %v", zap.DebugLevel, GetLevel())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	setLogLevel("info")
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(`{"level":"debug"}`))
+	LevelHandler().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %v, got %v", http.StatusOK, recorder.Code)
+	}
+	if GetLevel() != zap.DebugLevel {
+		t.Fatalf("Expected %v, got %v", zap.DebugLevel, GetLevel())
+	}
+}
+
+func TestInitLoggerEncoding(t *testing.T) {
+	tests := []struct {
+		encoding    string
+		expectError bool
+	}{
+		{"json", false},
+		{"console", false},
+		{"xml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			options := DefaultOptions()
+			options.Encoding = tt.encoding
+
+			err := InitLogger(options)
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for encoding %v", tt.encoding)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for encoding %v, got %v", tt.encoding, err)
+			}
+		})
+	}
+}
+
+func TestInitLoggerSampling(t *testing.T) {
+	// Arrange
+	options := DefaultOptions()
+	options.FileLog = true
+	options.FilePath = filepath.Join(t.TempDir(), "logs", "gpu-metrics.log")
+	options.Sampling = true
+	options.SamplingInitial = 3
+	options.SamplingThereafter = 4
+	if err := InitLogger(options); err != nil {
+		t.Fatal(err)
+	}
+	defer GetLogger("info", false, "")
+
+	// Act
+	for i := 0; i < 10; i++ {
+		Error("Error collecting fan speed metrics")
+	}
+	Error("Error collecting power info metrics")
+	Sync()
+
+	// Assert
+	content, err := os.ReadFile(options.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Count(string(content), "\n")
+	if lines != 5 {
+		t.Errorf("Expected 3 initial, 1 thereafter and 1 distinct entries, got %v", lines)
+	}
+}
+
+func TestInitLoggerSamplingByDefault(t *testing.T) {
+	// Arrange
+	options := DefaultOptions()
+	options.FileLog = true
+	options.FilePath = filepath.Join(t.TempDir(), "logs", "gpu-metrics.log")
+	if err := InitLogger(options); err != nil {
+		t.Fatal(err)
+	}
+	defer GetLogger("info", false, "")
+
+	// Act
+	for i := 0; i < 20; i++ {
+		Error("Error collecting fan speed metrics")
+		Info("GPU device set changed, clearing cached label values")
+	}
+	Sync()
+
+	// Assert
+	content, err := os.ReadFile(options.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors := strings.Count(string(content), "Error collecting fan speed metrics"); errors != options.SamplingInitial {
+		t.Errorf("Expected the repeated error to be sampled to %v entries, got %v", options.SamplingInitial, errors)
+	}
+	if infos := strings.Count(string(content), "GPU device set changed"); infos != 20 {
+		t.Errorf("Expected all 20 info entries, got %v", infos)
+	}
+}
@@ -1,6 +1,13 @@
+// Package nvmlerrors extends the NVML return codes with the errors of the exporter.
+// Errors are classified into kinds, so callers can decide to skip, retry or disable a collector:
+//
+//	if errors.Is(err, nvmlerrors.ErrNotSupported) { // skip }
+//	if errors.Is(err, nvmlerrors.ErrTimeout) { // retry }
+//	var ret nvml.Return; errors.As(err, &ret) // the wrapped NVML code
 package nvmlerrors
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
@@ -10,48 +17,130 @@ import (
 type MetricReturn struct {
 	Return      nvml.Return
 	MetricError int32
+	Op          string // the metric, label or collector the error occurred for
 }
 
 const (
 	ERR_NONE              = iota
 	METRIC_NOT_REGISTERED = 1000
-	LABEL_NOT_REGISTERED  = 1001
+	LABEL_NOT_REGISTERED  = 1001 // no label function for the label, the label is missing
+	NOT_SUPPORTED         = 1002
+	TIMEOUT               = 1003
+	DEVICE_LOST           = 1004
+	DRIVER_ERROR          = 1005
 )
 
+// Sentinel errors to match the kind of an error with errors.Is
+var (
+	ErrMetricNotRegistered = &MetricReturn{MetricError: METRIC_NOT_REGISTERED}
+	ErrLabelNotRegistered  = &MetricReturn{MetricError: LABEL_NOT_REGISTERED}
+	ErrNotSupported        = &MetricReturn{MetricError: NOT_SUPPORTED}
+	ErrTimeout             = &MetricReturn{MetricError: TIMEOUT}
+	ErrDeviceLost          = &MetricReturn{MetricError: DEVICE_LOST}
+	ErrDriver              = &MetricReturn{MetricError: DRIVER_ERROR}
+)
+
+var errorNames = map[int32]string{
+	ERR_NONE:              "ERR_NONE",
+	METRIC_NOT_REGISTERED: "ERR_METRIC_NOT_REGISTERED",
+	LABEL_NOT_REGISTERED:  "ERR_LABEL_NOT_REGISTERED",
+	NOT_SUPPORTED:         "ERR_NOT_SUPPORTED",
+	TIMEOUT:               "ERR_TIMEOUT",
+	DEVICE_LOST:           "ERR_DEVICE_LOST",
+	DRIVER_ERROR:          "ERR_DRIVER",
+}
+
 func (e *MetricReturn) Error() string {
 	return e.String()
 }
 
 // String method
 func (e *MetricReturn) String() string {
-	// if metric error is less than or equal to the return error, return the return nvml error
-	if e.MetricError <= int32(e.Return) {
-		return e.Return.Error()
-	}
-	switch e.MetricError {
-	case ERR_NONE:
-		return nvml.SUCCESS.String()
-	case METRIC_NOT_REGISTERED:
-		return "ERR_METRIC_NOT_REGISTERED"
+	message := errorNames[e.MetricError]
+	if e.Return != nvml.SUCCESS {
+		message = fmt.Sprintf("%v: %v", message, e.Return.Error())
+	}
+	if e.Op != "" {
+		message = fmt.Sprintf("%v: %v", e.Op, message)
+	}
+	return message
+}
+
+// Is matches errors of the same kind, see the sentinel errors.
+func (e *MetricReturn) Is(target error) bool {
+	t, ok := target.(*MetricReturn)
+	if !ok {
+		return false
+	}
+	return e.MetricError == t.MetricError && e.MetricError != ERR_NONE
+}
+
+// Unwrap returns the NVML return code, so errors.Is and errors.As match the NVML codes as well.
+func (e *MetricReturn) Unwrap() error {
+	if e.Return == nvml.SUCCESS {
+		return nil
+	}
+	return e.Return
+}
+
+// New wraps the NVML return code of the operation, it returns nil for nvml.SUCCESS.
+func New(ret nvml.Return, op string) error {
+	if ret == nvml.SUCCESS {
+		return nil
+	}
+	return &MetricReturn{Return: ret, MetricError: classify(ret), Op: op}
+}
+
+// MetricNotRegistered returns the error for a metric missing from the metrics config.
+func MetricNotRegistered(metric string) error {
+	return &MetricReturn{MetricError: METRIC_NOT_REGISTERED, Op: metric}
+}
+
+// LabelNotRegistered returns the error for a label without a label function.
+func LabelNotRegistered(label string) error {
+	return &MetricReturn{MetricError: LABEL_NOT_REGISTERED, Op: label}
+}
+
+// classify maps the NVML return code to the kind of error.
+func classify(ret nvml.Return) int32 {
+	switch ret {
+	case nvml.ERROR_NOT_SUPPORTED, nvml.ERROR_FUNCTION_NOT_FOUND:
+		return NOT_SUPPORTED
+	case nvml.ERROR_TIMEOUT:
+		return TIMEOUT
+	case nvml.ERROR_GPU_IS_LOST, nvml.ERROR_RESET_REQUIRED:
+		return DEVICE_LOST
+	case nvml.ERROR_UNINITIALIZED, nvml.ERROR_DRIVER_NOT_LOADED, nvml.ERROR_LIBRARY_NOT_FOUND, nvml.ERROR_LIB_RM_VERSION_MISMATCH:
+		return DRIVER_ERROR
 	default:
-		return e.Return.Error()
+		return ERR_NONE
 	}
 }
 
-// ErrorInit is a function that initializes the MetricReturn struct
-func ErrorInit() *MetricReturn {
-	return &MetricReturn{}
+// IsNotSupported reports whether the error means the device doesn't support the operation, it can be skipped.
+func IsNotSupported(err error) bool {
+	return errors.Is(err, ErrNotSupported)
+}
 
+// IsRetryable reports whether the operation may succeed when retried.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrTimeout)
 }
 
-// @TODO implement
-// Errormain is a function that demonstrates the use of the MetricReturn struct
-func Errormain() {
-	me := ErrorInit()
-	// me.MetricError = METRIC_NOT_REGISTERED
-	fmt.Println(me.Error())
+// IsDeviceLost reports whether the device fell off the bus or needs a reset.
+func IsDeviceLost(err error) bool {
+	return errors.Is(err, ErrDeviceLost)
+}
 
-	me.Return = nvml.ERROR_OPERATING_SYSTEM
-	fmt.Println(me.Error())
+// ReturnCode returns the NVML return code wrapped in the error, nvml.SUCCESS for nil.
+func ReturnCode(err error) nvml.Return {
+	if err == nil {
+		return nvml.SUCCESS
+	}
 
+	var ret nvml.Return
+	if errors.As(err, &ret) {
+		return ret
+	}
+	return nvml.ERROR_UNKNOWN
 }
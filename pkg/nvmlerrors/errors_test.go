@@ -0,0 +1,118 @@
+package nvmlerrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		ret    nvml.Return
+		target error
+	}{
+		{"not supported", nvml.ERROR_NOT_SUPPORTED, ErrNotSupported},
+		{"function not found", nvml.ERROR_FUNCTION_NOT_FOUND, ErrNotSupported},
+		{"timeout", nvml.ERROR_TIMEOUT, ErrTimeout},
+		{"gpu lost", nvml.ERROR_GPU_IS_LOST, ErrDeviceLost},
+		{"reset required", nvml.ERROR_RESET_REQUIRED, ErrDeviceLost},
+		{"driver not loaded", nvml.ERROR_DRIVER_NOT_LOADED, ErrDriver},
+		{"uninitialized", nvml.ERROR_UNINITIALIZED, ErrDriver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("collect: %w", New(tt.ret, "gpu_temperature"))
+
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Expected %v to be %v", err, tt.target)
+			}
+			if !errors.Is(err, tt.ret) {
+				t.Errorf("Expected %v to wrap %v", err, tt.ret)
+			}
+
+			var ret nvml.Return
+			if !errors.As(err, &ret) || ret != tt.ret {
+				t.Errorf("Expected the wrapped return code %v, got %v", tt.ret, ret)
+			}
+			if ReturnCode(err) != tt.ret {
+				t.Errorf("Expected return code %v, got %v", tt.ret, ReturnCode(err))
+			}
+		})
+	}
+}
+
+func TestNew_Success(t *testing.T) {
+	if err := New(nvml.SUCCESS, "gpu_temperature"); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+	if ReturnCode(nil) != nvml.SUCCESS {
+		t.Errorf("Expected SUCCESS for a nil error, got %v", ReturnCode(nil))
+	}
+}
+
+func TestNew_Unclassified(t *testing.T) {
+	err := New(nvml.ERROR_NO_PERMISSION, "gpu_power_limit")
+
+	for _, target := range []error{ErrNotSupported, ErrTimeout, ErrDeviceLost, ErrDriver, ErrMetricNotRegistered} {
+		if errors.Is(err, target) {
+			t.Errorf("Expected %v not to be %v", err, target)
+		}
+	}
+	if !errors.Is(err, nvml.ERROR_NO_PERMISSION) {
+		t.Errorf("Expected %v to wrap ERROR_NO_PERMISSION", err)
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	if !IsNotSupported(New(nvml.ERROR_NOT_SUPPORTED, "op")) {
+		t.Error("Expected ERROR_NOT_SUPPORTED to be not supported")
+	}
+	if !IsRetryable(New(nvml.ERROR_TIMEOUT, "op")) {
+		t.Error("Expected ERROR_TIMEOUT to be retryable")
+	}
+	if IsRetryable(New(nvml.ERROR_NOT_SUPPORTED, "op")) {
+		t.Error("Expected ERROR_NOT_SUPPORTED not to be retryable")
+	}
+	if !IsDeviceLost(New(nvml.ERROR_GPU_IS_LOST, "op")) {
+		t.Error("Expected ERROR_GPU_IS_LOST to be device lost")
+	}
+}
+
+func TestNotRegistered(t *testing.T) {
+	err := fmt.Errorf("skipping metric: %w", MetricNotRegistered("gpu_temperature"))
+	if !errors.Is(err, ErrMetricNotRegistered) {
+		t.Errorf("Expected %v to be ErrMetricNotRegistered", err)
+	}
+	if errors.Is(err, ErrLabelNotRegistered) {
+		t.Errorf("Expected %v not to be ErrLabelNotRegistered", err)
+	}
+	if ReturnCode(err) != nvml.ERROR_UNKNOWN {
+		t.Errorf("Expected ERROR_UNKNOWN, got %v", ReturnCode(err))
+	}
+
+	var metricErr *MetricReturn
+	if !errors.As(LabelNotRegistered("gpu_id"), &metricErr) || metricErr.MetricError != LABEL_NOT_REGISTERED {
+		t.Errorf("Expected a LABEL_NOT_REGISTERED MetricReturn, got %v", metricErr)
+	}
+}
+
+func TestMetricReturn_String(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{MetricNotRegistered("gpu_temperature"), "gpu_temperature: ERR_METRIC_NOT_REGISTERED"},
+		{LabelNotRegistered("gpu_id"), "gpu_id: ERR_LABEL_NOT_REGISTERED"},
+		{&MetricReturn{MetricError: TIMEOUT}, "ERR_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
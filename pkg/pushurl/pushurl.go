@@ -0,0 +1,27 @@
+// Package pushurl validates and redacts the URLs of the push targets, the OTLP endpoint, the remote-write
+// receiver and the Pushgateway.
+package pushurl
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Validate returns an error unless the URL is an http or https URL with a host.
+// The target names the URL in the error, e.g. "Pushgateway URL".
+func Validate(target string, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid %s %q, expected an http or https URL", target, rawURL)
+	}
+	return nil
+}
+
+// Redact hides the password of the URL for logging.
+func Redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
@@ -55,3 +55,21 @@ func GetProjectDir() (string, error) {
 	wd = strings.Replace(wd, "/internal/utils", "", -1)
 	return wd, nil
 }
+
+// ParsePairs parses name=value pairs separated by commas, e.g. labels or headers given as a flag.
+func ParsePairs(pairs string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(pairs, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid pair %q, expected name=value", pair)
+		}
+		parsed[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return parsed, nil
+}
@@ -61,3 +61,27 @@ func TestLoadFromYAMLV2_WithInvalidModel(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestParsePairs(t *testing.T) {
+	tests := []struct {
+		pairs   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"ci_job_id=1234, benchmark = resnet50", map[string]string{"ci_job_id": "1234", "benchmark": "resnet50"}, false},
+		{"Authorization=Basic dXNlcjpwYXNz==", map[string]string{"Authorization": "Basic dXNlcjpwYXNz=="}, false},
+		{"ci_job_id", nil, true},
+		{"=1234", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePairs(tt.pairs)
+		if tt.wantErr {
+			assert.Error(t, err, tt.pairs)
+			continue
+		}
+		assert.NoError(t, err, tt.pairs)
+		assert.Equal(t, tt.want, got, tt.pairs)
+	}
+}
@@ -0,0 +1,37 @@
+// Package version holds the build information of the exporter.
+// Version and Commit are set at build time:
+// go build -ldflags "-X github.com/rupeshtr78/nvidia-metrics/pkg/version.Version=v1.0.0 -X github.com/rupeshtr78/nvidia-metrics/pkg/version.Commit=$(git rev-parse HEAD)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// GetCommit returns the commit set at build time, or the vcs revision recorded by the go toolchain.
+func GetCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return "unknown"
+}
+
+// GoVersion returns the go version the exporter was built with.
+func GoVersion() string {
+	return runtime.Version()
+}